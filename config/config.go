@@ -4,29 +4,55 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/go-playground/validator"
 	"github.com/mcuadros/go-defaults"
+	"github.com/raystack/salt/observability/logger"
+	"github.com/spf13/afero"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v3"
 )
 
 // Loader is responsible for managing configuration
 type Loader struct {
-	v     *viper.Viper
-	flags *pflag.FlagSet
+	mu            sync.RWMutex
+	v             *viper.Viper
+	flags         *pflag.FlagSet
+	sources       []Source
+	sourceTimeout time.Duration
+	sourceReports []SourceReport
+	overlays      []string
+	profile       string
+	activeProfile string
+	migrations    []Migration
+	strict        bool
+	encryptionKey func(fs afero.Fs) (string, error)
+	logger        logger.Logger
+	fs            afero.Fs
+	overrides     map[string]interface{}
+
+	// State recorded by the last Load, used to explain where values came from.
+	layers        []layer
+	defaultValues map[string]interface{}
+	values        map[string]interface{}
+	flagNames     map[string]string
+	secretKeys    map[string]bool
+	aliases       map[string]string
+	deprecated    map[string]string
+	encrypted     map[string]encryptedValue
+	diagnostics   []Diagnostic
 }
 
 // Option defines a functional option for configuring the Loader.
 type Option func(c *Loader)
 
 // NewLoader creates a new Loader instance with the provided options.
-// It initializes Viper with defaults for YAML configuration files and environment variable handling.
+// It initializes Viper with defaults for configuration files and environment variable handling.
 //
 // Example:
 //
@@ -35,21 +61,24 @@ type Option func(c *Loader)
 //	    config.WithEnvPrefix("MYAPP"),
 //	)
 func NewLoader(options ...Option) *Loader {
+	loader := &Loader{v: newViper(), logger: logger.NewNoop(), fs: afero.NewOsFs()}
+	for _, opt := range options {
+		opt(loader)
+	}
+	return loader
+}
+
+func newViper() *viper.Viper {
 	v := viper.New()
 
 	v.SetConfigName("config")
 	v.SetConfigType("yaml")
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
-
-	loader := &Loader{v: v}
-	for _, opt := range options {
-		opt(loader)
-	}
-	return loader
+	return v
 }
 
-// WithFile specifies the configuration file to use.
+// WithFile specifies the configuration file to use. See WithFiles for overlays.
 func WithFile(configFilePath string) Option {
 	return func(l *Loader) {
 		l.v.SetConfigFile(configFilePath)
@@ -70,92 +99,235 @@ func WithFlags(flags *pflag.FlagSet) Option {
 	}
 }
 
-// WithAppConfig sets up application-specific configuration file handling.
+// WithAppConfig sets up application-specific configuration file handling, using
+// `<config dir>/raystack/<app>.yml`. The directory is created by `Init` or `Save`
+// when the file is first written.
 func WithAppConfig(app string) Option {
 	return func(l *Loader) {
-		filePath, err := getConfigFilePath(app)
-		if err != nil {
-			panic(fmt.Errorf("failed to determine config file path: %w", err))
-		}
-		l.v.SetConfigFile(filePath)
+		l.v.SetConfigFile(getConfigFilePath(app))
+	}
+}
+
+// WithFs sets the filesystem that configuration files, includes, `${file:...}` placeholders
+// and migrations are read from and written to. It defaults to the OS filesystem; an in-memory
+// filesystem (`afero.NewMemMapFs()`) makes the loader testable hermetically. `Watch` requires
+// the OS filesystem.
+func WithFs(fs afero.Fs) Option {
+	return func(l *Loader) {
+		l.fs = fs
+		l.v.SetFs(fs)
 	}
 }
 
 // Load reads the configuration from the file, environment variables, and command-line flags,
 // and merges them into the provided configuration struct. It validates the configuration
-// using struct tags.
+// using struct tags and returns a *ValidationError listing every invalid field.
 //
 // The priority order is:
 //  1. Command-line flags
 //  2. Environment variables
-//  3. Configuration file
-//  4. Default values
+//  3. Additional sources registered with WithSource
+//  4. Configuration file
+//  5. Default values
 func (l *Loader) Load(config interface{}) error {
+	return l.load(config, false)
+}
+
+// load loads the configuration into a scratch loader and adopts its state, so that Get, View
+// and Explain reflect the loaded configuration. With onlyValid, the state is only adopted when
+// the configuration is valid, so that a rejected reload leaves no trace.
+func (l *Loader) load(config interface{}, onlyValid bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if err := validateStructPtr(config); err != nil {
 		return err
 	}
 
+	scratch := l.scratch()
+	err := scratch.loadInto(config)
+	if err == nil || !onlyValid {
+		l.adopt(scratch)
+	}
+	return err
+}
+
+// scratch returns a loader with the options of l and a fresh viper instance, holding no
+// state of previous loads.
+func (l *Loader) scratch() *Loader {
+	v := newViper()
+	v.SetFs(l.fs)
+	if file := l.v.ConfigFileUsed(); file != "" {
+		v.SetConfigFile(file)
+	}
+	v.SetEnvPrefix(l.v.GetEnvPrefix())
+	for key, value := range l.overrides {
+		v.Set(key, value)
+	}
+	return &Loader{
+		v:             v,
+		flags:         l.flags,
+		sources:       l.sources,
+		sourceTimeout: l.sourceTimeout,
+		overlays:      l.overlays,
+		profile:       l.profile,
+		migrations:    l.migrations,
+		strict:        l.strict,
+		encryptionKey: l.encryptionKey,
+		logger:        l.logger,
+		fs:            l.fs,
+		overrides:     l.overrides,
+	}
+}
+
+// adopt takes over the viper instance and the state recorded by a load of s.
+func (l *Loader) adopt(s *Loader) {
+	l.v = s.v
+	l.sourceReports = s.sourceReports
+	l.activeProfile = s.activeProfile
+	l.layers = s.layers
+	l.defaultValues = s.defaultValues
+	l.values = s.values
+	l.flagNames = s.flagNames
+	l.secretKeys = s.secretKeys
+	l.aliases = s.aliases
+	l.deprecated = s.deprecated
+	l.encrypted = s.encrypted
+	l.diagnostics = s.diagnostics
+}
+
+// loadInto reads the configuration into config, recording its state in l.
+func (l *Loader) loadInto(config interface{}) error {
 	// Apply default values before reading configuration
 	defaults.SetDefaults(config)
 
 	// Bind flags dynamically using reflection on `cmdx` tags if a flag set is provided
+	l.flagNames = make(map[string]string)
 	if l.flags != nil {
-		if err := bindFlags(l.v, l.flags, reflect.TypeOf(config).Elem(), ""); err != nil {
+		if err := bindFlags(l.v, l.flags, reflect.TypeOf(config).Elem(), "", l.flagNames); err != nil {
 			return fmt.Errorf("failed to bind flags: %w", err)
 		}
 	}
 
-	// Bind environment variables for all keys in the config
+	l.aliases = make(map[string]string)
+	l.deprecated = make(map[string]string)
+	collectAliases(reflect.TypeOf(config), "", l.aliases, l.deprecated)
+
+	// Bind environment variables for all keys in the config, falling back to those of aliases
 	keys, err := extractFlattenedKeys(config)
 	if err != nil {
 		return fmt.Errorf("failed to extract config keys: %w", err)
 	}
 	for _, key := range keys {
-		if err := l.v.BindEnv(key); err != nil {
+		input := []string{key}
+		if aliasEnvs := l.aliasEnvVars(strings.ToLower(key)); len(aliasEnvs) > 0 {
+			input = append(input, l.envVarName(key))
+			input = append(input, aliasEnvs...)
+		}
+		if err := l.v.BindEnv(input...); err != nil {
 			return fmt.Errorf("failed to bind environment variable for key %q: %w", key, err)
 		}
 	}
 
-	// Attempt to read the configuration file
-	if err := l.v.ReadInConfig(); err != nil {
-		var configFileNotFoundError viper.ConfigFileNotFoundError
-		if errors.As(err, &configFileNotFoundError) {
-			fmt.Println("Warning: Config file not found. Falling back to defaults and environment variables.")
-		}
+	l.secretKeys = secretKeys(reflect.TypeOf(config))
+
+	// Remember the defaults so that Explain can tell them apart from other sources
+	if l.defaultValues, err = flattenStruct(config); err != nil {
+		return fmt.Errorf("failed to extract default values: %w", err)
+	}
+
+	// Attempt to read the configuration file, its overlays, profile variants and includes
+	fileLayers, err := l.readConfigFiles()
+	if err != nil {
+		return err
+	}
+	fileValues := mergeLayers(fileLayers)
+
+	// Resolve ${...} placeholders; unresolved ones are reported together with validation errors
+	interpolationErrs := interpolate(fileValues, l.fs)
+
+	// Decrypt ENC[...] values after interpolation, so that plaintexts are never interpolated
+	l.encrypted = make(map[string]encryptedValue)
+	if err := l.decryptValues(fileValues, fileLayers); err != nil {
+		return err
+	}
+	if err := l.v.MergeConfigMap(unflattenMap(fileValues)); err != nil {
+		return fmt.Errorf("failed to merge config files: %w", err)
+	}
+	l.layers = fileLayers
+
+	// Merge external sources on top of the configuration file
+	if err := l.mergeSources(); err != nil {
+		return err
+	}
+
+	// In strict mode, keys that do not map to a field are reported together with validation errors
+	var unknown []FieldError
+	if l.strict {
+		unknown = l.unknownKeys(reflect.TypeOf(config))
 	}
 
 	// Unmarshal the merged configuration into the provided struct
 	if err := l.v.Unmarshal(config); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
+		return fmt.Errorf("failed to unmarshal config: %w%s", err, l.locateKeys(quotedKeys(err.Error())))
+	}
+	if l.values, err = flattenStruct(config); err != nil {
+		return fmt.Errorf("failed to extract config values: %w", err)
 	}
+	l.warnDeprecated()
 
 	// Validate the resulting configuration
-	if err := validator.New().Struct(config); err != nil {
-		return fmt.Errorf("invalid configuration: %w", err)
+	if err := l.validationError(newValidator().Struct(config), interpolationErrs, unknown); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// Init initializes the configuration file with default values.
-func (l *Loader) Init(config interface{}) error {
+// Init initializes the configuration file with default values. The file format
+// is chosen from its extension: YAML, JSON, TOML, HCL or dotenv (`.env`).
+//
+// YAML files are written as a commented template: every key is documented with its
+// `desc` tag, allowed `oneof` values, environment variable, flag and default. Use
+// `WithOptionalSections` to include commented-out optional sections and `WithMerge`
+// to add new keys to an existing file instead of failing.
+func (l *Loader) Init(config interface{}, opts ...InitOption) error {
+	if err := validateStructPtr(config); err != nil {
+		return err
+	}
+	var o initOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
 	defaults.SetDefaults(config)
 
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	path := l.v.ConfigFileUsed()
-	if fileExists(path) {
+	if path == "" {
+		return errors.New("no configuration file specified for initialization")
+	}
+	existing, err := afero.ReadFile(l.fs, path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		existing = nil
+	case err != nil:
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	case !o.merge:
 		return errors.New("configuration file already exists")
 	}
 
-	data, err := yaml.Marshal(config)
+	data, err := l.scaffold(fileFormat(path), config, o, existing)
 	if err != nil {
 		return fmt.Errorf("failed to marshal configuration: %w", err)
 	}
 
-	if err := ensureDir(filepath.Dir(path)); err != nil {
-		return fmt.Errorf("failed to create directory: %w", err)
+	if err := ensureDir(l.fs, filepath.Dir(path)); err != nil {
+		return err
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := afero.WriteFile(l.fs, path, data, 0644); err != nil {
 		return fmt.Errorf("failed to write configuration file: %w", err)
 	}
 	return nil
@@ -163,36 +335,89 @@ func (l *Loader) Init(config interface{}) error {
 
 // Get retrieves a configuration value by key.
 func (l *Loader) Get(key string) interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
 	return l.v.Get(key)
 }
 
 // Set updates a configuration value in memory (not persisted to file).
 func (l *Loader) Set(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.overrides == nil {
+		l.overrides = make(map[string]interface{})
+	}
+	l.overrides[key] = value
 	l.v.Set(key, value)
 }
 
-// Save writes the current configuration to the file specified during initialization.
+// Save writes the configuration of the base file specified during initialization, along
+// with the values set with Set, in the format given by its extension. Values of overlays,
+// profile variants, included files and sources are not written to the base file. Saving
+// over an existing YAML file keeps its comments and key order.
+//
+// Secrets, the values of fields tagged `secret:"true"`, are never written in plaintext. Those
+// supplied by environment variables are written as references to them, and those set with Set
+// are encrypted with the configured encryption key; without one, Save fails.
 func (l *Loader) Save() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
 	configFile := l.v.ConfigFileUsed()
 	if configFile == "" {
 		return errors.New("no configuration file specified for saving")
 	}
 
-	settings := l.v.AllSettings()
-	content, err := yaml.Marshal(settings)
+	existing, err := afero.ReadFile(l.fs, configFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	settings, err := l.savedSettings()
+	if err != nil {
+		return err
+	}
+	content, err := l.encodeConfig(fileFormat(configFile), settings, existing)
 	if err != nil {
 		return fmt.Errorf("failed to marshal configuration: %w", err)
 	}
 
-	if err := os.WriteFile(configFile, content, 0644); err != nil {
+	if err := ensureDir(l.fs, filepath.Dir(configFile)); err != nil {
+		return err
+	}
+	if err := afero.WriteFile(l.fs, configFile, content, 0644); err != nil {
 		return fmt.Errorf("failed to write configuration to file: %w", err)
 	}
 	return nil
 }
 
-// View returns the current configuration as a formatted JSON string.
+// savedSettings returns the settings Save writes: the values of the base file as written there,
+// so that placeholders and encrypted values are kept, overridden by the values set with Set.
+// Values of overlays, profile variants, includes, sources, environment variables and flags
+// are left out, apart from references to the environment variables supplying secrets.
+func (l *Loader) savedSettings() (map[string]interface{}, error) {
+	base := l.v.ConfigFileUsed()
+	var layers []layer
+	for _, lyr := range l.layers {
+		if lyr.path == base {
+			layers = append(layers, lyr)
+		}
+	}
+	overrides := flattenMap(l.overrides)
+	layers = append(layers, layer{values: overrides})
+	flat := l.secretReferences(mergeLayers(layers))
+	if err := l.encryptSetSecrets(flat, overrides); err != nil {
+		return nil, err
+	}
+	return unflattenMap(flat), nil
+}
+
+// View returns the current configuration as a formatted JSON string with secret values masked.
 func (l *Loader) View() (string, error) {
-	settings := l.v.AllSettings()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	settings := l.redactSettings(l.v.AllSettings())
 	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to format configuration as JSON: %w", err)
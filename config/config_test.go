@@ -102,6 +102,11 @@ func TestMissingConfigFile(t *testing.T) {
 	if err := loader.Load(cfg); err != nil {
 		t.Errorf("Unexpected error for missing config file: %v", err)
 	}
+
+	diagnostics := loader.Diagnostics()
+	if len(diagnostics) != 1 || diagnostics[0].Fields["path"] != "./nonexistent_config.yaml" {
+		t.Errorf("Expected a diagnostic for the missing config file, got %v", diagnostics)
+	}
 }
 
 func TestInvalidConfigurationValidation(t *testing.T) {
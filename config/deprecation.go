@@ -0,0 +1,129 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// collectAliases records, for every field of the struct type t, the keys listed in its `alias` tag
+// (absolute dotted keys, e.g. `alias:"log.level,loglevel"`) and the reason given in its
+// `deprecated` tag.
+func collectAliases(t reflect.Type, prefix string, aliases, deprecated map[string]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := fieldKey(field, prefix)
+		for _, alias := range strings.Split(field.Tag.Get("alias"), ",") {
+			if alias = strings.ToLower(strings.TrimSpace(alias)); alias != "" {
+				aliases[alias] = key
+			}
+		}
+		if reason, ok := field.Tag.Lookup("deprecated"); ok {
+			deprecated[key] = reason
+		}
+		collectAliases(field.Type, key, aliases, deprecated)
+	}
+}
+
+// resolveAlias returns the current key for a key that is, or is nested below, an alias.
+func (l *Loader) resolveAlias(key string) (string, bool) {
+	for alias, current := range l.aliases {
+		if key == alias {
+			return current, true
+		}
+		if strings.HasPrefix(key, alias+".") {
+			return current + key[len(alias):], true
+		}
+	}
+	return "", false
+}
+
+// aliasEnvVars returns the environment variables of the aliases of the key, in a stable order.
+func (l *Loader) aliasEnvVars(key string) []string {
+	var names []string
+	for alias, current := range l.aliases {
+		switch {
+		case key == current:
+			names = append(names, l.envVarName(alias))
+		case strings.HasPrefix(key, current+"."):
+			names = append(names, l.envVarName(alias+key[len(current):]))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// applyAliases moves values of the layer set under aliases to their current keys and warns
+// about them. Values set under the current key in the same layer take precedence.
+func (l *Loader) applyAliases(lyr *layer) {
+	if len(l.aliases) == 0 {
+		return
+	}
+	keys := make([]string, 0, len(lyr.values))
+	for key := range lyr.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		current, ok := l.resolveAlias(key)
+		if !ok {
+			continue
+		}
+		location := lyr.fileLocation(key)
+		if location == "" {
+			location = lyr.name
+		}
+		l.warn("deprecated configuration key", "key", key, "replacement", current, "location", location)
+
+		value := lyr.values[key]
+		delete(lyr.values, key)
+		if _, exists := lyr.values[current]; exists {
+			continue
+		}
+		lyr.values[current] = value
+		if line, ok := lyr.lines[key]; ok {
+			lyr.lines[current] = line
+		}
+	}
+}
+
+// warnDeprecated warns about environment variables named after aliases and about values
+// explicitly set for fields tagged `deprecated`.
+func (l *Loader) warnDeprecated() {
+	keys := make([]string, 0, len(l.values))
+	for key := range l.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		lkey := strings.ToLower(key)
+		for _, name := range l.aliasEnvVars(lkey) {
+			if os.Getenv(name) != "" {
+				l.warn("deprecated environment variable", "env", name, "replacement", l.envVarName(lkey))
+			}
+		}
+
+		for deprecated, reason := range l.deprecated {
+			if lkey != deprecated && !strings.HasPrefix(lkey, deprecated+".") {
+				continue
+			}
+			switch p := l.explainKey(key); p.Origin {
+			case OriginDefault, OriginFlagDefault, "":
+			default:
+				l.warn("deprecated configuration key", "key", key, "reason", reason, "location", l.location(key))
+			}
+		}
+	}
+}
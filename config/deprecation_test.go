@@ -0,0 +1,107 @@
+package config_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+	"github.com/raystack/salt/observability/logger"
+)
+
+type AliasConfig struct {
+	Server struct {
+		Port int    `mapstructure:"port" alias:"port,http.port"`
+		Host string `mapstructure:"host"`
+	} `mapstructure:"server"`
+	Database struct {
+		URL string `mapstructure:"url"`
+	} `mapstructure:"database" alias:"db"`
+	LogLevel string `mapstructure:"log_level" alias:"log.level"`
+	Workers  int    `mapstructure:"workers" deprecated:"workers are sized automatically"`
+}
+
+func TestAliasesInFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "port: 9090\ndb:\n  url: postgres://db\nlog:\n  level: debug\nworkers: 4\n")
+
+	var buf bytes.Buffer
+	loader := config.NewLoader(
+		config.WithFile(path),
+		config.WithLogger(logger.NewLogrus(logger.LogrusWithWriter(&buf))),
+	)
+	cfg := &AliasConfig{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if cfg.Server.Port != 9090 || cfg.Database.URL != "postgres://db" || cfg.LogLevel != "debug" || cfg.Workers != 4 {
+		t.Errorf("Expected aliases to map onto current fields, got %+v", cfg)
+	}
+
+	logs := buf.String()
+	for _, want := range []string{
+		"key=port", "replacement=server.port", "location=\"" + path + ":1\"",
+		"key=db.url", "replacement=database.url",
+		"key=log.level", "replacement=log_level",
+		"key=workers", "reason=\"workers are sized automatically\"",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("Expected warnings to contain %q, got:\n%s", want, logs)
+		}
+	}
+
+	provenance, err := loader.Explain()
+	if err != nil {
+		t.Fatalf("Failed to explain configuration: %v", err)
+	}
+	if port := findProvenance(t, provenance, "server.port"); port.Location != path+":1" {
+		t.Errorf("Expected aliased key to keep its location, got %+v", port)
+	}
+}
+
+func TestCurrentKeyWinsOverAlias(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "port: 9090\nserver:\n  port: 8080\n")
+
+	cfg := &AliasConfig{}
+	if err := config.NewLoader(config.WithFile(path)).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 8080 {
+		t.Errorf("Expected current key to take precedence, got %d", cfg.Server.Port)
+	}
+}
+
+func TestAliasEnvironmentVariables(t *testing.T) {
+	setEnv(t, "MYAPP_HTTP_PORT", "7070")
+	defer unsetEnv(t, "MYAPP_HTTP_PORT")
+	setEnv(t, "MYAPP_DB_URL", "postgres://env")
+	defer unsetEnv(t, "MYAPP_DB_URL")
+
+	var buf bytes.Buffer
+	loader := config.NewLoader(
+		config.WithEnvPrefix("MYAPP"),
+		config.WithLogger(logger.NewLogrus(logger.LogrusWithWriter(&buf))),
+	)
+	cfg := &AliasConfig{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 7070 || cfg.Database.URL != "postgres://env" {
+		t.Errorf("Expected alias environment variables to apply, got %+v", cfg)
+	}
+	if logs := buf.String(); !strings.Contains(logs, "env=MYAPP_HTTP_PORT") || !strings.Contains(logs, "replacement=MYAPP_SERVER_PORT") {
+		t.Errorf("Expected a warning for the deprecated environment variable, got:\n%s", logs)
+	}
+
+	setEnv(t, "MYAPP_SERVER_PORT", "6060")
+	defer unsetEnv(t, "MYAPP_SERVER_PORT")
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 6060 {
+		t.Errorf("Expected current environment variable to take precedence, got %d", cfg.Server.Port)
+	}
+}
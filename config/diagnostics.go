@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/raystack/salt/observability/logger"
+)
+
+// Diagnostic is a non-fatal problem noticed during `Load`, such as a missing configuration
+// file or a deprecated key.
+type Diagnostic struct {
+	Message string
+	Fields  map[string]interface{} // Details such as "path", "key" or "replacement".
+}
+
+func (d Diagnostic) String() string {
+	keys := make([]string, 0, len(d.Fields))
+	for key := range d.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{d.Message}
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, d.Fields[key]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// WithLogger sets the logger that diagnostics, such as deprecated keys, are reported to
+// as warnings during `Load`. Warnings are discarded by default.
+func WithLogger(log logger.Logger) Option {
+	return func(l *Loader) {
+		l.logger = log
+	}
+}
+
+// Diagnostics returns the diagnostics reported during the last call to `Load`.
+func (l *Loader) Diagnostics() []Diagnostic {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]Diagnostic(nil), l.diagnostics...)
+}
+
+// warn records a diagnostic and logs it as a warning. args are alternating key/value pairs.
+func (l *Loader) warn(msg string, args ...interface{}) {
+	d := Diagnostic{Message: msg, Fields: make(map[string]interface{}, len(args)/2)}
+	for i := 0; i+1 < len(args); i += 2 {
+		d.Fields[fmt.Sprint(args[i])] = args[i+1]
+	}
+	l.diagnostics = append(l.diagnostics, d)
+	l.logger.Warn(msg, args...)
+}
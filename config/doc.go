@@ -8,8 +8,28 @@ Configuration Precedence:
 The `Loader` merges configuration values from multiple sources in the following order of precedence (highest to lowest):
  1. Command-line flags: Defined using `pflag.FlagSet` and dynamically bound via `cmdx` tags.
  2. Environment variables: Dynamically bound to configuration keys, optionally prefixed using `WithEnvPrefix`.
- 3. Configuration file: YAML configuration files specified via `WithFile`.
- 4. Default values: Struct fields annotated with `default` tags are populated if no other source provides a value.
+ 3. Additional sources: Secret stores, key/value services, mounted secret directories or HTTP endpoints
+    registered via `WithSource`. Sources registered later override earlier ones.
+ 4. Configuration files: YAML, JSON, TOML, HCL or dotenv files specified via `WithFile` or `WithFiles`.
+ 5. Default values: Struct fields annotated with `default` tags are populated if no other source provides a value.
+
+Sources:
+Any type implementing the `Source` interface can supply values. The package ships with `VaultSource`
+(Vault-style KV), `ConsulSource` (Consul-style KV), `DirSource` (a directory of mounted secret files),
+`HTTPSource` (a JSON endpoint) and `MapSource` (in-memory values). `Loader.Sources` reports the keys
+each source supplied during the last `Load`.
+
+Example:
+
+	loader := config.NewLoader(
+	    config.WithFile("./config.yaml"),
+	    config.WithSource(&config.DirSource{Path: "/var/run/secrets/myapp"}),
+	    config.WithSource(&config.VaultSource{
+	        Address: "https://vault.example.com",
+	        Path:    "secret/data/myapp",
+	        Token:   os.Getenv("VAULT_TOKEN"),
+	    }),
+	)
 
 Defaults:
 Default values are specified using the `default` struct tag. Fields annotated with `default` are populated
@@ -35,13 +55,25 @@ Example:
 	    LogLevel   string `mapstructure:"log.level" validate:"required,oneof=debug info warn error"`
 	}
 
-If validation fails, the `Load` method returns a detailed error indicating the invalid fields.
+If validation fails, the `Load` method returns a `*ValidationError` listing each invalid field with its
+dotted key, the environment variable and flag that set it, the failed rule and the offending value
+(masked for secrets). `Rows` renders it as a table:
+
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+	    printer.Table(os.Stderr, verr.Rows())
+	}
 
 Annotations:
 Configuration structs use the following struct tags to define behavior:
-  - `mapstructure`: Maps YAML or environment variables to struct fields.
+  - `mapstructure`: Maps configuration file keys or environment variables to struct fields.
   - `default`: Provides fallback values for fields when no source overrides them.
   - `validate`: Ensures the final configuration meets application-specific requirements.
+  - `secret`: Marks a field as sensitive (`secret:"true"`), see Secrets below.
+  - `cmdx`: Names the command-line flag bound to the field.
+  - `desc`: Describes the field, used as flag usage and schema description.
+  - `alias`: Lists former keys of the field, see Deprecations below.
+  - `deprecated`: Marks the field as deprecated with a reason, see Deprecations below.
 
 Example:
 
@@ -56,11 +88,183 @@ Example:
 
 The `Loader` will merge all sources, apply defaults, and validate the result in a single call to `Load`.
 
+File Formats:
+The format of each file is detected from its extension: `.yaml`/`.yml` (and files without a known extension),
+`.json`, `.toml`, `.hcl` and `.env`. Dotenv files suit env-only deployments; their variables use the same names
+as environment variables (e.g. `MYAPP_SERVER_PORT`). `Init` scaffolds and `Save` writes the file in its own
+format; saving over an existing YAML file keeps its comments and key order.
+
+Scaffolding:
+`Init` writes a configuration file filled with defaults. YAML files become a commented template: each key is
+documented with its `desc` tag, the allowed values of a `oneof` rule, its environment variable, flag and default.
+`WithOptionalSections` adds optional sections (pointer-to-struct fields) commented out, and `WithMerge`
+regenerates an existing file by adding missing keys while keeping its values and comments:
+
+	# Port to listen on
+	# env: MYAPP_SERVER_PORT | flag: --server.port | default: 8080 | required
+	port: 8080
+
+	err := loader.Init(&Config{}, config.WithMerge())
+
+Deprecations:
+Renamed keys keep working through the `alias` tag, which lists former dotted keys (absolute, comma-separated).
+Values set under an alias in files or sources, or through the alias's environment variable, are mapped onto
+the field; the current key wins when both are set. Fields tagged `deprecated:"reason"` still load. In both
+cases `Load` emits a structured warning naming the replacement or reason through the logger set with
+`WithLogger` (warnings are discarded by default):
+
+	type Config struct {
+	    LogLevel string `mapstructure:"log_level" alias:"log.level"`
+	    Workers  int    `mapstructure:"workers" deprecated:"workers are sized automatically"`
+	}
+
+	loader := config.NewLoader(config.WithLogger(logger.NewZap()))
+
+Strict Mode:
+By default keys that do not map to a struct field are ignored, so a typo such as `max_open_con` silently
+leaves the field at its default. With `WithStrict`, `Load` returns a `*ValidationError` listing every such
+key set in a file or source, and every `<PREFIX>_*` environment variable when a prefix is set, with its
+location and a suggestion for likely typos. Keys nested below map fields are always accepted.
+
+	loader := config.NewLoader(config.WithFile("./config.yaml"), config.WithEnvPrefix("MYAPP"), config.WithStrict())
+	// invalid configuration: db.max_open_con: unknown key, did you mean "db.max_open_conns"? (set at ./config.yaml:4)
+
+Encrypted Values:
+Scalar values in files and sources may be encrypted with AES-256-GCM, so that files holding credentials can
+be committed. `Encrypt` produces values of the form `ENC[AES256_GCM,...]` under a base64-encoded key from
+`GenerateKey`; `Load` decrypts them with the key set by `WithEncryptionKey`, `WithEncryptionKeyFile` or
+`WithEncryptionKeyEnv` and treats them as secrets, including encrypted elements of lists. `Save` writes
+unchanged values back encrypted, and `Reencrypt` (or `ReencryptFile`) atomically rotates every value of
+a file to a new key.
+
+	value, _ := config.Encrypt(key, "s3cret") // database.password: ENC[AES256_GCM,...]
+	loader := config.NewLoader(config.WithFile("./config.yaml"), config.WithEncryptionKeyFile("./config.key"))
+
+Migrations:
+Configuration files record their version in a top-level `version` key. Migrations registered with
+`WithMigrations` transform the raw values of older files, e.g. to rename or restructure keys (`MoveKey`).
+`Load` applies pending migrations in memory so outdated files keep working, and `Migrate` rewrites the
+base file after backing it up to `<path>.v<version>.bak`. `Migrate(config.WithDryRun())` only reports the
+unified diff. `Init` writes new files in the latest version.
+
+	report, err := loader.Migrate(config.WithDryRun())
+	if err == nil {
+	    fmt.Print(report.Diff)
+	}
+
+Overlays, Profiles and Includes:
+`WithFiles` accepts a base file followed by overlays that override it in order, e.g. a local override file.
+A profile selected with `WithProfile`, a `--profile` flag or the `<PREFIX>_PROFILE` environment variable merges
+`<name>.<profile><ext>` on top of each file when present. A file may list fragments to merge beneath itself
+using a top-level `include` key with paths relative to the file. All files are merged deeply before unmarshal,
+and decoding or validation errors name the file (and, for YAML and dotenv, the line) that set the offending value.
+
+Example:
+
+	# config.yaml
+	include:
+	  - shared/logging.yaml
+	server:
+	  port: 8080
+
+	loader := config.NewLoader(
+	    config.WithFiles("./config.yaml", "./config.local.yaml"),
+	    config.WithProfile("staging"), // merges ./config.staging.yaml when present
+	)
+
+Interpolation:
+String values in configuration files may contain placeholders that are resolved before unmarshal:
+  - `${NAME}`: The environment variable NAME.
+  - `${NAME:-default}`: The environment variable NAME, or default if it is unset or empty.
+  - `${file:/run/secrets/db}`: The trimmed content of a file.
+  - `${ref:db.host}`: The value of another configuration key. Reference cycles are detected.
+  - `$${`: A literal `${`.
+
+Placeholders that cannot be resolved are reported in the `ValidationError` (rule "interpolate"), and
+remain reachable as `InterpolationError`s through `errors.As`.
+
+Secrets:
+Fields tagged with `secret:"true"` or declared with the `Secret` type are masked by `View` and `Explain`,
+and `Save` never writes them in plaintext: values supplied by their environment variable are written as
+references to it (e.g. `${MYAPP_DB_PASSWORD}`), and values set with `Set` are encrypted with the
+configured encryption key, without which `Save` fails. Values of type `Secret` are also masked when
+formatted with the fmt package, and `Redact` returns a masked copy of a configuration struct that is
+safe to log.
+
+Example:
+
+	type Config struct {
+	    DB struct {
+	        URL      string        `mapstructure:"url" secret:"true"`
+	        Password config.Secret `mapstructure:"password"`
+	    } `mapstructure:"db"`
+	}
+
+Flags:
+Fields tagged with `cmdx` are bound to flags of the same name; a missing flag is an error. Instead of
+registering each flag by hand, `RegisterFlags` generates them from the struct with types, defaults
+(`default`) and usage (`desc`), including durations, slices, string maps and nested structs:
+
+	flags := pflag.NewFlagSet("example", pflag.ExitOnError)
+	if err := config.RegisterFlags(flags, &Config{}); err != nil {
+	    log.Fatal(err)
+	}
+
+Provenance:
+`Loader.Explain` reports, for every key of the loaded struct, which origin supplied the winning value
+(flag, env, a source, the file or a default), the lower-priority values it overrode, and the environment
+variable and flag that would set it. `Loader.ExplainView` renders the same report as JSON. Values of keys
+that look like secrets (passwords, tokens, ...) are masked.
+
+JSON Schema:
+`GenerateSchema` and `GenerateSchemaJSON` derive a JSON Schema document from the configuration struct so that
+editors can autocomplete configuration files and CI can lint them without running the binary. Property names
+follow `mapstructure` tags, defaults follow `default` tags and common `validate` rules (required, min, max,
+oneof, ...) become schema constraints. Fields with a default are never required, since files may leave them out.
+
+Diagnostics and Filesystems:
+The loader does not print to stdout or touch the filesystem outside of `Init`, `Save` and `Migrate`.
+Non-fatal problems noticed by `Load`, such as a missing configuration file or deprecated keys, are
+returned by `Loader.Diagnostics` and logged as warnings through the logger set with `WithLogger`.
+`WithAppConfig` only computes the file path; its directory is created when the file is first written.
+`WithFs` replaces the OS filesystem, e.g. with an in-memory one for hermetic tests:
+
+	fs := afero.NewMemMapFs()
+	afero.WriteFile(fs, "/etc/app/config.yaml", []byte("server:\n  port: 8080\n"), 0644)
+	loader := config.NewLoader(config.WithFs(fs), config.WithFile("/etc/app/config.yaml"))
+
+Live Reload:
+Long-running services can keep a configuration struct up to date with its file using `Watch`.
+Each change is re-read together with environment variables, defaulted and validated; invalid
+edits are rejected through `OnError` callbacks and the previous configuration stays in effect.
+
+Example:
+
+	w, err := config.Watch[Config](ctx, loader)
+	if err != nil {
+	    log.Fatal(err)
+	}
+	w.Subscribe(func(c config.Change[Config]) {
+	    log.Printf("reloaded configuration, changed keys: %v", c.Changed)
+	})
+
 Features:
-  - Merges configurations from multiple sources: flags, environment variables, files, and defaults.
+  - Merges configurations from multiple sources: flags, environment variables, external sources, files, and defaults.
+  - Layers multiple files with overlays, profiles and includes.
   - Supports nested structs with dynamic field mapping using `cmdx` tags.
+  - Generates command-line flags from `cmdx` tags.
   - Validates fields with constraints defined in `validate` tags.
-  - Saves and views the final configuration in YAML or JSON formats.
+  - Reads, initializes and saves YAML, JSON, TOML, HCL and dotenv files.
+  - Scaffolds commented configuration templates from struct tags.
+  - Decrypts encrypted values and rotates their key.
+  - Rejects unknown keys in strict mode, suggesting the intended ones.
+  - Migrates configuration files across versions with backups and dry-run diffs.
+  - Accepts renamed keys through aliases and warns about deprecated keys.
+  - Reports diagnostics instead of printing, and runs on any afero filesystem.
+  - Views the final configuration as JSON.
+  - Generates a JSON Schema for configuration files.
+  - Explains where every configuration value came from.
+  - Reloads the configuration when its file changes and notifies typed subscribers.
 
 Example Usage:
 
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// Encrypted values have the form ENC[AES256_GCM,<base64 nonce and ciphertext>].
+const (
+	encryptedPrefix = "ENC[AES256_GCM,"
+	encryptedSuffix = "]"
+	keySize         = 32
+)
+
+var encryptedPattern = regexp.MustCompile(`ENC\[AES256_GCM,[A-Za-z0-9+/=]+\]`)
+
+// encryptedValue records a value decrypted by the last Load, a string or a list holding
+// encrypted elements, so that `Save` can write the ciphertext back as long as the value is unchanged.
+type encryptedValue struct {
+	ciphertext interface{}
+	plaintext  interface{}
+}
+
+// WithEncryptionKey sets the base64-encoded AES-256 key used to decrypt encrypted values.
+func WithEncryptionKey(key string) Option {
+	return func(l *Loader) {
+		l.encryptionKey = func(afero.Fs) (string, error) {
+			return key, nil
+		}
+	}
+}
+
+// WithEncryptionKeyFile reads the base64-encoded AES-256 key used to decrypt encrypted values
+// from a file. The file is only read when `Load` encounters an encrypted value.
+func WithEncryptionKeyFile(path string) Option {
+	return func(l *Loader) {
+		l.encryptionKey = func(fs afero.Fs) (string, error) {
+			data, err := afero.ReadFile(fs, path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read encryption key file: %w", err)
+			}
+			return strings.TrimSpace(string(data)), nil
+		}
+	}
+}
+
+// WithEncryptionKeyEnv reads the base64-encoded AES-256 key used to decrypt encrypted values
+// from the environment variable name.
+func WithEncryptionKeyEnv(name string) Option {
+	return func(l *Loader) {
+		l.encryptionKey = func(afero.Fs) (string, error) {
+			key := os.Getenv(name)
+			if key == "" {
+				return "", fmt.Errorf("encryption key environment variable %s is not set", name)
+			}
+			return key, nil
+		}
+	}
+}
+
+// GenerateKey returns a new random base64-encoded AES-256 key.
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted reports whether the value is an encrypted value produced by Encrypt.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix) && strings.HasSuffix(value, encryptedSuffix)
+}
+
+// Encrypt encrypts plaintext with AES-256-GCM under the base64-encoded key. The result, e.g.
+// "ENC[AES256_GCM,...]", can be used as a scalar value in any configuration file.
+func Encrypt(key, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed) + encryptedSuffix, nil
+}
+
+// Decrypt decrypts a value produced by Encrypt with the base64-encoded key.
+func Decrypt(key, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSuffix(strings.TrimPrefix(value, encryptedPrefix), encryptedSuffix))
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("malformed encrypted value: too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// ReencryptFile decrypts every encrypted value in the configuration file at path with oldKey
+// and encrypts it again with newKey, leaving the rest of the file untouched. It is a shorthand
+// for `Reencrypt` on a loader of the file on the OS filesystem.
+func ReencryptFile(path, oldKey, newKey string) error {
+	return NewLoader(WithFile(path)).Reencrypt(oldKey, newKey)
+}
+
+// Reencrypt decrypts every encrypted value in the configuration file of the loader, including
+// list elements, with oldKey and encrypts it again with newKey, leaving the rest of the file
+// untouched. The file is read from and written to the filesystem set by `WithFs`; it is replaced
+// atomically, so that a failed write never leaves a partially rotated file behind.
+func (l *Loader) Reencrypt(oldKey, newKey string) error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	path := l.v.ConfigFileUsed()
+	if path == "" {
+		return errors.New("no configuration file specified for re-encryption")
+	}
+	info, err := l.fs.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	content, err := afero.ReadFile(l.fs, path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	var reencryptErr error
+	content = encryptedPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		if reencryptErr != nil {
+			return match
+		}
+		plaintext, err := Decrypt(oldKey, string(match))
+		if err != nil {
+			reencryptErr = err
+			return match
+		}
+		value, err := Encrypt(newKey, plaintext)
+		if err != nil {
+			reencryptErr = err
+			return match
+		}
+		return []byte(value)
+	})
+	if reencryptErr != nil {
+		return fmt.Errorf("failed to re-encrypt %s: %w", path, reencryptErr)
+	}
+
+	if err := writeFileAtomic(l.fs, path, content, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write configuration file: %w", err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes content to a temporary file next to path and renames it over path.
+func writeFileAtomic(fs afero.Fs, path string, content []byte, perm os.FileMode) error {
+	tmp, err := afero.TempFile(fs, filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer fs.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := fs.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return fs.Rename(tmp.Name(), path)
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	if len(raw) != keySize {
+		return nil, fmt.Errorf("invalid encryption key: expected %d bytes, got %d", keySize, len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// decryptValues decrypts the encrypted values among flattened values in place, including the
+// elements of lists. layers are used to report where a value that cannot be decrypted was set.
+// Decrypted keys are treated as secrets.
+func (l *Loader) decryptValues(values map[string]interface{}, layers []layer) error {
+	var key string
+	decrypt := func(name, s string) (string, error) {
+		if key == "" {
+			var err error
+			if key, err = l.resolveEncryptionKey(); err != nil {
+				return "", fmt.Errorf("failed to decrypt %s%s: %w", name, setAt(layers, name), err)
+			}
+		}
+		plaintext, err := Decrypt(key, s)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt %s%s: %w", name, setAt(layers, name), err)
+		}
+		return plaintext, nil
+	}
+
+	for name, value := range values {
+		switch v := value.(type) {
+		case string:
+			if !IsEncrypted(v) {
+				continue
+			}
+			plaintext, err := decrypt(name, v)
+			if err != nil {
+				return err
+			}
+			values[name] = plaintext
+			l.encrypted[name] = encryptedValue{ciphertext: v, plaintext: plaintext}
+			l.secretKeys[name] = true
+		case []interface{}:
+			var decrypted []interface{}
+			for i, item := range v {
+				s, ok := item.(string)
+				if !ok || !IsEncrypted(s) {
+					continue
+				}
+				plaintext, err := decrypt(fmt.Sprintf("%s[%d]", name, i), s)
+				if err != nil {
+					return err
+				}
+				if decrypted == nil {
+					decrypted = append([]interface{}(nil), v...)
+				}
+				decrypted[i] = plaintext
+			}
+			if decrypted != nil {
+				values[name] = decrypted
+				l.encrypted[name] = encryptedValue{ciphertext: v, plaintext: decrypted}
+				l.secretKeys[name] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (l *Loader) resolveEncryptionKey() (string, error) {
+	if l.encryptionKey == nil {
+		return "", errors.New("no encryption key configured")
+	}
+	return l.encryptionKey(l.fs)
+}
+
+// setAt describes where the highest priority of layers set the key, e.g. " (set at config.yaml:3)".
+func setAt(layers []layer, key string) string {
+	for i := len(layers) - 1; i >= 0; i-- {
+		if _, ok := layers[i].values[key]; !ok {
+			continue
+		}
+		location := layers[i].fileLocation(key)
+		if location == "" {
+			location = layers[i].name
+		}
+		return " (set at " + location + ")"
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package config_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+	"github.com/spf13/afero"
+)
+
+type EncryptedConfig struct {
+	Database struct {
+		Host     string `mapstructure:"host"`
+		Password string `mapstructure:"password"`
+	} `mapstructure:"database"`
+	Port int `mapstructure:"port"`
+}
+
+func newKey(t *testing.T) string {
+	t.Helper()
+	key, err := config.GenerateKey()
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key
+}
+
+func encrypt(t *testing.T, key, plaintext string) string {
+	t.Helper()
+	value, err := config.Encrypt(key, plaintext)
+	if err != nil {
+		t.Fatalf("Failed to encrypt value: %v", err)
+	}
+	return value
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := newKey(t)
+	value := encrypt(t, key, "hunter2")
+	if !config.IsEncrypted(value) || strings.Contains(value, "hunter2") {
+		t.Fatalf("Unexpected encrypted value: %s", value)
+	}
+
+	plaintext, err := config.Decrypt(key, value)
+	if err != nil || plaintext != "hunter2" {
+		t.Errorf("Expected to decrypt hunter2, got %q (%v)", plaintext, err)
+	}
+	if _, err := config.Decrypt(newKey(t), value); err == nil {
+		t.Error("Expected decryption with another key to fail")
+	}
+	if _, err := config.Encrypt("c2hvcnQ=", "x"); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestLoadDecryptsValues(t *testing.T) {
+	key := newKey(t)
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "config.key")
+	writeConfigFile(t, keyFile, key+"\n")
+	path := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, path, "database:\n  host: db\n  password: "+encrypt(t, key, "hunter2")+"\nport: "+encrypt(t, key, "8080")+"\n")
+
+	loader := config.NewLoader(config.WithFile(path), config.WithEncryptionKeyFile(keyFile))
+	cfg := &EncryptedConfig{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Database.Password != "hunter2" || cfg.Port != 8080 {
+		t.Errorf("Expected decrypted values, got %+v", cfg)
+	}
+
+	view, err := loader.View()
+	if err != nil {
+		t.Fatalf("Failed to view configuration: %v", err)
+	}
+	if strings.Contains(view, "hunter2") {
+		t.Errorf("Expected decrypted values to be masked, got:\n%s", view)
+	}
+
+	// Unchanged values are saved encrypted
+	if err := loader.Save(); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	saved := readFile(t, path)
+	if strings.Contains(saved, "hunter2") || !strings.Contains(saved, "ENC[AES256_GCM,") {
+		t.Errorf("Expected saved file to keep encrypted values, got:\n%s", saved)
+	}
+}
+
+func TestLoadEncryptedValueErrors(t *testing.T) {
+	key := newKey(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "database:\n  password: "+encrypt(t, key, "hunter2")+"\n")
+
+	err := config.NewLoader(config.WithFile(path)).Load(&EncryptedConfig{})
+	if err == nil || !strings.Contains(err.Error(), "no encryption key configured") || !strings.Contains(err.Error(), path+":2") {
+		t.Errorf("Expected a missing key error with location, got: %v", err)
+	}
+
+	setEnv(t, "MYAPP_CONFIG_KEY", newKey(t))
+	defer unsetEnv(t, "MYAPP_CONFIG_KEY")
+	err = config.NewLoader(config.WithFile(path), config.WithEncryptionKeyEnv("MYAPP_CONFIG_KEY")).Load(&EncryptedConfig{})
+	if err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("Expected a wrong key error, got: %v", err)
+	}
+}
+
+func TestReencryptFile(t *testing.T) {
+	oldKey, newKey := newKey(t), newKey(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "# credentials\ndatabase:\n  host: db\n  password: "+encrypt(t, oldKey, "hunter2")+"\n")
+
+	if err := config.ReencryptFile(path, oldKey, newKey); err != nil {
+		t.Fatalf("Failed to re-encrypt file: %v", err)
+	}
+	if content := readFile(t, path); !strings.HasPrefix(content, "# credentials\ndatabase:\n  host: db\n") {
+		t.Errorf("Expected the rest of the file to be untouched, got:\n%s", content)
+	}
+
+	cfg := &EncryptedConfig{}
+	if err := config.NewLoader(config.WithFile(path), config.WithEncryptionKey(newKey)).Load(cfg); err != nil {
+		t.Fatalf("Failed to load re-encrypted configuration: %v", err)
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Errorf("Expected re-encrypted password, got %q", cfg.Database.Password)
+	}
+
+	if err := config.ReencryptFile(path, oldKey, newKey); err == nil {
+		t.Error("Expected re-encryption with the wrong key to fail")
+	}
+}
+
+func TestReencryptUsesLoaderFs(t *testing.T) {
+	oldKey, newKey := newKey(t), newKey(t)
+	fs := afero.NewMemMapFs()
+	content := "database:\n  password: " + encrypt(t, oldKey, "hunter2") + "\n"
+	if err := afero.WriteFile(fs, "/etc/myapp/config.yaml", []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	loader := config.NewLoader(config.WithFs(fs), config.WithFile("/etc/myapp/config.yaml"))
+	if err := loader.Reencrypt(oldKey, newKey); err != nil {
+		t.Fatalf("Failed to re-encrypt file: %v", err)
+	}
+
+	cfg := &EncryptedConfig{}
+	if err := config.NewLoader(config.WithFs(fs), config.WithFile("/etc/myapp/config.yaml"), config.WithEncryptionKey(newKey)).Load(cfg); err != nil {
+		t.Fatalf("Failed to load re-encrypted configuration: %v", err)
+	}
+	if cfg.Database.Password != "hunter2" {
+		t.Errorf("Expected re-encrypted password, got %q", cfg.Database.Password)
+	}
+	entries, err := afero.ReadDir(fs, "/etc/myapp")
+	if err != nil || len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left behind, got %v (%v)", entries, err)
+	}
+	if info, err := fs.Stat("/etc/myapp/config.yaml"); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v (%v)", info.Mode(), err)
+	}
+}
+
+func TestLoadDecryptsListElements(t *testing.T) {
+	key := newKey(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	tokens := []string{encrypt(t, key, "alpha"), "plain"}
+	writeConfigFile(t, path, "tokens:\n  - "+tokens[0]+"\n  - "+tokens[1]+"\n")
+
+	cfg := &struct {
+		Tokens []string `mapstructure:"tokens"`
+	}{}
+	loader := config.NewLoader(config.WithFile(path), config.WithEncryptionKey(key))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Tokens, []string{"alpha", "plain"}) {
+		t.Errorf("Expected decrypted list elements, got %v", cfg.Tokens)
+	}
+
+	if err := loader.Save(); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	if content := readFile(t, path); strings.Contains(content, "alpha") || !strings.Contains(content, tokens[0]) {
+		t.Errorf("Expected the list element to be saved encrypted, got:\n%s", content)
+	}
+}
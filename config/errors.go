@@ -0,0 +1,144 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+// RuleInterpolate is the rule reported for values whose placeholders could not be resolved.
+const RuleInterpolate = "interpolate"
+
+// FieldError describes a configuration key that failed validation.
+type FieldError struct {
+	Key      string      `json:"key"`                // Dotted configuration key, e.g. "server.port".
+	EnvVar   string      `json:"env_var"`            // Environment variable that sets the key.
+	Flag     string      `json:"flag,omitempty"`     // Flag that sets the key, if bound.
+	Location string      `json:"location,omitempty"` // Where the offending value was set, e.g. "config.yaml:12".
+	Rule     string      `json:"rule"`               // Failed rule, e.g. "min" or "interpolate".
+	Param    string      `json:"param,omitempty"`    // Rule parameter, e.g. "1" for "min=1".
+	Value    interface{} `json:"value"`              // Offending value, masked for secrets.
+	Message  string      `json:"message"`
+}
+
+// ValidationError is returned by `Load` when the configuration is invalid. It lists every
+// failing field so that CLIs can tell users exactly which key to fix:
+//
+//	var verr *config.ValidationError
+//	if errors.As(err, &verr) {
+//	    printer.Table(os.Stderr, verr.Rows())
+//	}
+type ValidationError struct {
+	Fields []FieldError
+	errs   []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msg := f.Key + ": " + f.Message
+		if f.Location != "" {
+			msg += " (set at " + f.Location + ")"
+		}
+		msgs = append(msgs, msg)
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the underlying validator and interpolation errors.
+func (e *ValidationError) Unwrap() []error {
+	return e.errs
+}
+
+// Rows returns the failing fields as table rows, starting with a header row,
+// suitable for `printer.Table`.
+func (e *ValidationError) Rows() [][]string {
+	rows := [][]string{{"KEY", "RULE", "VALUE", "ENV", "FLAG", "LOCATION"}}
+	for _, f := range e.Fields {
+		rule := f.Rule
+		if f.Param != "" {
+			rule += "=" + f.Param
+		}
+		flag := ""
+		if f.Flag != "" {
+			flag = "--" + f.Flag
+		}
+		rows = append(rows, []string{f.Key, rule, fmt.Sprint(f.Value), f.EnvVar, flag, f.Location})
+	}
+	return rows
+}
+
+// validationError collects interpolation failures, unknown keys and validator failures into a
+// ValidationError. It returns nil when there are none.
+func (l *Loader) validationError(validationErr error, interpolationErrs []*InterpolationError, unknown []FieldError) error {
+	verr := &ValidationError{}
+	for _, ierr := range interpolationErrs {
+		verr.errs = append(verr.errs, ierr)
+		verr.Fields = append(verr.Fields, l.fieldError(ierr.Key, RuleInterpolate, "", ierr.Placeholder,
+			fmt.Sprintf("cannot resolve %s: %s", ierr.Placeholder, ierr.Reason)))
+	}
+	verr.Fields = append(verr.Fields, unknown...)
+
+	if validationErr != nil {
+		verr.errs = append(verr.errs, validationErr)
+		var fieldErrs validator.ValidationErrors
+		if !errors.As(validationErr, &fieldErrs) {
+			return fmt.Errorf("invalid configuration: %w", validationErr)
+		}
+		for _, fe := range fieldErrs {
+			verr.Fields = append(verr.Fields, l.fieldError(namespaceKey(fe.Namespace()), fe.Tag(), fe.Param(), fe.Value(),
+				ruleMessage(fe.Tag(), fe.Param())))
+		}
+	}
+
+	if len(verr.Fields) == 0 {
+		return nil
+	}
+	return verr
+}
+
+func (l *Loader) fieldError(key, rule, param string, value interface{}, message string) FieldError {
+	if l.isSecret(key) {
+		value = mask(value)
+	}
+	return FieldError{
+		Key:      key,
+		EnvVar:   l.envVarName(key),
+		Flag:     l.flagNames[key],
+		Location: l.location(key),
+		Rule:     rule,
+		Param:    param,
+		Value:    value,
+		Message:  message,
+	}
+}
+
+// ruleMessage describes a failed validator rule in plain words.
+func ruleMessage(rule, param string) string {
+	switch rule {
+	case "required":
+		return "is required"
+	case "min":
+		return "must be at least " + param
+	case "max":
+		return "must be at most " + param
+	case "len":
+		return "must have length " + param
+	case "gt":
+		return "must be greater than " + param
+	case "gte":
+		return "must be greater than or equal to " + param
+	case "lt":
+		return "must be less than " + param
+	case "lte":
+		return "must be less than or equal to " + param
+	case "oneof":
+		return "must be one of [" + param + "]"
+	}
+	if param != "" {
+		return fmt.Sprintf("failed the %q rule with parameter %q", rule, param)
+	}
+	return fmt.Sprintf("failed the %q rule", rule)
+}
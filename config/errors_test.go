@@ -0,0 +1,71 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/validator"
+	"github.com/raystack/salt/config"
+	"github.com/spf13/pflag"
+)
+
+func TestValidationErrorListsFields(t *testing.T) {
+	type ValidatedConfig struct {
+		Server struct {
+			Port int    `mapstructure:"port" validate:"min=1" cmdx:"port"`
+			Mode string `mapstructure:"mode" validate:"oneof=dev prod" cmdx:"mode"`
+		} `mapstructure:"server" cmdx:"server"`
+		Token string `mapstructure:"token" validate:"len=4" secret:"true" cmdx:"token"`
+	}
+
+	configFilePath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, configFilePath, "server:\n  port: 0\n  mode: test\n")
+
+	setEnv(t, "MYAPP_TOKEN", "hunter2")
+	defer unsetEnv(t, "MYAPP_TOKEN")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("server.port", 0, "Server port")
+	flags.String("server.mode", "", "Server mode")
+	flags.String("token", "", "API token")
+
+	err := config.NewLoader(
+		config.WithFile(configFilePath),
+		config.WithEnvPrefix("MYAPP"),
+		config.WithFlags(flags),
+	).Load(&ValidatedConfig{})
+
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a ValidationError, got %T: %v", err, err)
+	}
+
+	want := []config.FieldError{
+		{Key: "server.port", EnvVar: "MYAPP_SERVER_PORT", Flag: "server.port", Location: configFilePath + ":2",
+			Rule: "min", Param: "1", Value: 0, Message: "must be at least 1"},
+		{Key: "server.mode", EnvVar: "MYAPP_SERVER_MODE", Flag: "server.mode", Location: configFilePath + ":3",
+			Rule: "oneof", Param: "dev prod", Value: "test", Message: "must be one of [dev prod]"},
+		{Key: "token", EnvVar: "MYAPP_TOKEN", Flag: "token", Location: "env MYAPP_TOKEN",
+			Rule: "len", Param: "4", Value: "****", Message: "must have length 4"},
+	}
+	if !reflect.DeepEqual(verr.Fields, want) {
+		t.Errorf("Unexpected field errors:\n got: %+v\nwant: %+v", verr.Fields, want)
+	}
+
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected secret to be masked, got: %v", err)
+	}
+
+	rows := verr.Rows()
+	if len(rows) != 4 || !reflect.DeepEqual(rows[1], []string{"server.port", "min=1", "0", "MYAPP_SERVER_PORT", "--server.port", configFilePath + ":2"}) {
+		t.Errorf("Unexpected rows: %v", rows)
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		t.Errorf("Expected the validator errors to be reachable, got %T", err)
+	}
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Origins reported by Explain for values that do not come from a file or a registered source.
+const (
+	OriginFlag        = "flag"
+	OriginEnv         = "env"
+	OriginFlagDefault = "flag-default"
+	OriginDefault     = "default"
+)
+
+const maskedValue = "****"
+
+// layer holds the flattened values a single file or source provided during Load.
+type layer struct {
+	name   string
+	values map[string]interface{}
+
+	// Set for configuration files only.
+	path  string
+	lines map[string]int
+}
+
+// Provenance explains where the value of a configuration key came from.
+type Provenance struct {
+	Key        string      `json:"key"`
+	Value      interface{} `json:"value"`
+	Origin     string      `json:"origin"`
+	Location   string      `json:"location,omitempty"`
+	Overridden []Override  `json:"overridden,omitempty"`
+	EnvVar     string      `json:"env_var"`
+	Flag       string      `json:"flag,omitempty"`
+}
+
+// Override is a lower-priority value that lost to the winning origin of a key.
+type Override struct {
+	Origin   string      `json:"origin"`
+	Value    interface{} `json:"value"`
+	Location string      `json:"location,omitempty"`
+}
+
+// Explain reports, for every configuration key of the struct passed to the last `Load`,
+// the origin of its value, the file and line that set it, the lower-priority values it overrode, and the environment
+// variable and flag that would set it. Values of fields tagged `secret:"true"` and of keys
+// that look like secrets are masked.
+//
+// Origins are, from highest to lowest priority: "flag", "env", the names of registered
+// sources (last registered first), "file:<path>", "flag-default" and "default".
+func (l *Loader) Explain() ([]Provenance, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.values == nil {
+		return nil, errors.New("configuration has not been loaded")
+	}
+
+	keys := make([]string, 0, len(l.values))
+	for k := range l.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]Provenance, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, l.explainKey(key))
+	}
+	return result, nil
+}
+
+// ExplainView returns the output of Explain as a formatted JSON string.
+func (l *Loader) ExplainView() (string, error) {
+	provenance, err := l.Explain()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(provenance, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to format provenance as JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+func (l *Loader) explainKey(key string) Provenance {
+	lkey := strings.ToLower(key)
+	p := Provenance{
+		Key:    key,
+		Value:  l.values[key],
+		EnvVar: l.envVarName(key),
+		Flag:   l.flagNames[lkey],
+	}
+
+	// Candidates ordered from the highest to the lowest priority.
+	var candidates []Override
+	if p.Flag != "" {
+		if f := l.flags.Lookup(p.Flag); f != nil && f.Changed {
+			candidates = append(candidates, Override{Origin: OriginFlag, Value: f.Value.String()})
+		}
+	}
+	for _, name := range append([]string{p.EnvVar}, l.aliasEnvVars(lkey)...) {
+		if val, ok := os.LookupEnv(name); ok && val != "" {
+			candidates = append(candidates, Override{Origin: OriginEnv, Value: val})
+			break
+		}
+	}
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		if val, ok := l.layers[i].values[lkey]; ok {
+			candidates = append(candidates, Override{Origin: l.layers[i].name, Value: val, Location: l.layers[i].fileLocation(lkey)})
+		}
+	}
+	if p.Flag != "" {
+		if f := l.flags.Lookup(p.Flag); f != nil && !f.Changed {
+			candidates = append(candidates, Override{Origin: OriginFlagDefault, Value: f.DefValue})
+		}
+	}
+	if val, ok := l.defaultValues[key]; ok {
+		candidates = append(candidates, Override{Origin: OriginDefault, Value: val})
+	}
+
+	if len(candidates) > 0 {
+		p.Origin = candidates[0].Origin
+		p.Location = candidates[0].Location
+		p.Overridden = candidates[1:]
+	}
+
+	if l.isSecret(key) {
+		p.Value = mask(p.Value)
+		for i := range p.Overridden {
+			p.Overridden[i].Value = mask(p.Overridden[i].Value)
+		}
+	}
+	return p
+}
+
+// envVarName returns the environment variable bound to the configuration key.
+func (l *Loader) envVarName(key string) string {
+	name := strings.ReplaceAll(key, ".", "_")
+	if prefix := l.v.GetEnvPrefix(); prefix != "" {
+		name = prefix + "_" + name
+	}
+	return strings.ToUpper(name)
+}
+
+// isSecretKey reports whether the key names a value that must not be displayed.
+func isSecretKey(key string) bool {
+	parts := strings.Split(strings.ToLower(key), ".")
+	name := parts[len(parts)-1]
+	for _, hint := range []string{"password", "secret", "token", "credential", "private_key", "api_key"} {
+		if strings.Contains(name, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// mask hides a value while keeping empty values recognisable.
+func mask(value interface{}) interface{} {
+	if value == nil || reflect.ValueOf(value).IsZero() {
+		return value
+	}
+	return maskedValue
+}
@@ -0,0 +1,104 @@
+package config_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+	"github.com/spf13/pflag"
+)
+
+func findProvenance(t *testing.T, provenance []config.Provenance, key string) config.Provenance {
+	t.Helper()
+	for _, p := range provenance {
+		if p.Key == key {
+			return p
+		}
+	}
+	t.Fatalf("No provenance reported for key %q", key)
+	return config.Provenance{}
+}
+
+func TestExplainReportsWinningAndOverriddenOrigins(t *testing.T) {
+	configFilePath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, configFilePath, "server:\n  port: 8080\n  host: file-host\nlog_level: info\n")
+
+	setEnv(t, "MYAPP_SERVER_PORT", "9090")
+	defer unsetEnv(t, "MYAPP_SERVER_PORT")
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("server.port", 1000, "Server port")
+	flags.String("server.host", "flag-host", "Server host")
+	flags.String("log_level", "warn", "Log level")
+	flags.Parse([]string{"--log_level=trace"})
+
+	loader := config.NewLoader(
+		config.WithFile(configFilePath),
+		config.WithEnvPrefix("MYAPP"),
+		config.WithFlags(flags),
+		config.WithSource(&config.MapSource{SourceName: "vault", Values: map[string]interface{}{"server.host": "vault-host"}}),
+	)
+	if err := loader.Load(&Config{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	provenance, err := loader.Explain()
+	if err != nil {
+		t.Fatalf("Failed to explain configuration: %v", err)
+	}
+
+	port := findProvenance(t, provenance, "server.port")
+	if port.Origin != config.OriginEnv || port.Value != 9090 || port.EnvVar != "MYAPP_SERVER_PORT" || port.Flag != "server.port" {
+		t.Errorf("Unexpected provenance for server.port: %+v", port)
+	}
+	wantOverridden := []config.Override{
+		{Origin: "file:" + configFilePath, Value: 8080, Location: configFilePath + ":2"},
+		{Origin: config.OriginFlagDefault, Value: "1000"},
+		{Origin: config.OriginDefault, Value: 8000},
+	}
+	if !reflect.DeepEqual(port.Overridden, wantOverridden) {
+		t.Errorf("Unexpected overridden values for server.port: %+v", port.Overridden)
+	}
+
+	if host := findProvenance(t, provenance, "server.host"); host.Origin != "vault" || host.Value != "vault-host" {
+		t.Errorf("Unexpected provenance for server.host: %+v", host)
+	}
+	if level := findProvenance(t, provenance, "log_level"); level.Origin != config.OriginFlag || level.Value != "trace" {
+		t.Errorf("Unexpected provenance for log_level: %+v", level)
+	}
+}
+
+func TestExplainMasksSecrets(t *testing.T) {
+	type SecretConfig struct {
+		DB struct {
+			Password string `mapstructure:"password"`
+		} `mapstructure:"db"`
+	}
+
+	setEnv(t, "DB_PASSWORD", "hunter2")
+	defer unsetEnv(t, "DB_PASSWORD")
+
+	loader := config.NewLoader()
+	if err := loader.Load(&SecretConfig{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	view, err := loader.ExplainView()
+	if err != nil {
+		t.Fatalf("Failed to explain configuration: %v", err)
+	}
+	if strings.Contains(view, "hunter2") {
+		t.Errorf("Expected secret to be masked, got: %s", view)
+	}
+	if !strings.Contains(view, `"env_var": "DB_PASSWORD"`) {
+		t.Errorf("Expected env var name in output, got: %s", view)
+	}
+}
+
+func TestExplainBeforeLoad(t *testing.T) {
+	if _, err := config.NewLoader().Explain(); err == nil {
+		t.Error("Expected an error when explaining before loading")
+	}
+}
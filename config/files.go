@@ -0,0 +1,258 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the top-level key listing files to merge beneath the file that declares it.
+const includeKey = "include"
+
+// profileKey is the configuration key whose flag or environment variable selects the profile.
+const profileKey = "profile"
+
+// WithFiles specifies an ordered list of configuration files. The first file is the base
+// configuration, used by `Init` and `Save`; each following file is an overlay whose values
+// override those of the files before it. Missing overlays are skipped.
+//
+// Example:
+//
+//	loader := config.NewLoader(
+//	    config.WithFiles("./config.yaml", "./config.local.yaml"),
+//	)
+func WithFiles(base string, overlays ...string) Option {
+	return func(l *Loader) {
+		l.v.SetConfigFile(base)
+		l.overlays = overlays
+	}
+}
+
+// WithProfile sets the default configuration profile. For every configuration file,
+// a profile variant named `<name>.<profile><ext>` (e.g. "config.production.yaml") is
+// merged directly on top of it when present.
+//
+// The profile can be overridden at load time by a `--profile` flag in the flag set passed
+// to `WithFlags`, or by the `<PREFIX>_PROFILE` environment variable.
+func WithProfile(profile string) Option {
+	return func(l *Loader) {
+		l.profile = profile
+	}
+}
+
+// Profile returns the profile selected during the last call to `Load`.
+func (l *Loader) Profile() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.activeProfile
+}
+
+// resolveProfile determines the active profile from the flag, environment or default, in that order.
+func (l *Loader) resolveProfile() string {
+	if l.flags != nil {
+		if f := l.flags.Lookup(profileKey); f != nil && f.Changed {
+			return f.Value.String()
+		}
+	}
+	if val := os.Getenv(l.envVarName(profileKey)); val != "" {
+		return val
+	}
+	return l.profile
+}
+
+// configFiles returns the configuration files in merge order, including profile variants.
+func (l *Loader) configFiles() []string {
+	var paths []string
+	if base := l.v.ConfigFileUsed(); base != "" {
+		paths = append(paths, base)
+	}
+	paths = append(paths, l.overlays...)
+
+	if l.activeProfile == "" {
+		return paths
+	}
+	files := make([]string, 0, 2*len(paths))
+	for _, path := range paths {
+		ext := filepath.Ext(path)
+		files = append(files, path, strings.TrimSuffix(path, ext)+"."+l.activeProfile+ext)
+	}
+	return files
+}
+
+// readConfigFiles reads the base file, its overlays, profile variants and includes into
+// one layer per file, ordered from the lowest to the highest priority.
+// A missing base file is not an error; the configuration then relies on other sources.
+func (l *Loader) readConfigFiles() ([]layer, error) {
+	l.activeProfile = l.resolveProfile()
+
+	base := l.v.ConfigFileUsed()
+	if base == "" {
+		return nil, nil
+	}
+
+	var layers []layer
+	for _, path := range l.configFiles() {
+		fileLayers, err := l.readConfigFile(path, nil)
+		if errors.Is(err, fs.ErrNotExist) {
+			if path == base {
+				l.warn("configuration file not found, falling back to defaults and environment variables", "path", base)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, fileLayers...)
+	}
+	return layers, nil
+}
+
+// readConfigFile reads a file in any supported format and the files it includes. Included
+// files are returned before the including file so that its own values take precedence.
+func (l *Loader) readConfigFile(path string, visiting []string) ([]layer, error) {
+	for _, p := range visiting {
+		if p == path {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(visiting, path), " -> "))
+		}
+	}
+
+	content, err := afero.ReadFile(l.fs, path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && len(visiting) > 0 {
+			return nil, fmt.Errorf("included file %s not found (included from %s)", path, visiting[len(visiting)-1])
+		}
+		return nil, err
+	}
+
+	values, lines, err := l.decodeConfig(fileFormat(path), content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if len(visiting) == 0 && path == l.v.ConfigFileUsed() && len(l.migrations) > 0 {
+		// Outdated base files are migrated in memory; `Migrate` rewrites them.
+		if _, err := l.migrate(values); err != nil {
+			return nil, fmt.Errorf("failed to migrate config file %s: %w", path, err)
+		}
+	}
+
+	includes, err := parseIncludes(values[includeKey])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delete(values, includeKey)
+	delete(lines, includeKey)
+
+	var layers []layer
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(path), inc)
+		}
+		included, err := l.readConfigFile(inc, append(visiting, path))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, included...)
+	}
+
+	lyr := layer{
+		name:   "file:" + path,
+		path:   path,
+		values: flattenMap(values),
+		lines:  lines,
+	}
+	l.applyAliases(&lyr)
+	return append(layers, lyr), nil
+}
+
+func parseIncludes(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		includes := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s entry %v: expected a file path", includeKey, item)
+			}
+			includes = append(includes, s)
+		}
+		return includes, nil
+	default:
+		return nil, fmt.Errorf("invalid %s value %v: expected a file path or a list of file paths", includeKey, value)
+	}
+}
+
+// collectLines records the line of every leaf value of a YAML mapping, keyed by lowercase dotted key.
+func collectLines(node *yaml.Node, prefix string, lines map[string]int) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := strings.ToLower(node.Content[i].Value)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		value := node.Content[i+1]
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			collectLines(value, key, lines)
+			continue
+		}
+		lines[key] = node.Content[i].Line
+	}
+}
+
+// mergeLayers deep merges the flattened values of layers, later layers overriding earlier ones.
+func mergeLayers(layers []layer) map[string]interface{} {
+	merged := make(map[string]interface{})
+	for _, lyr := range layers {
+		for key, value := range lyr.values {
+			// A value replaces everything nested below it and any value it is nested below.
+			for existing := range merged {
+				if strings.HasPrefix(existing, key+".") || strings.HasPrefix(key, existing+".") {
+					delete(merged, existing)
+				}
+			}
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// location returns where the winning value of the key was set, e.g. "config.yaml:12",
+// "env MYAPP_SERVER_PORT" or "flag --server.port". It is empty for defaults.
+func (l *Loader) location(key string) string {
+	p := l.explainKey(key)
+	switch {
+	case p.Location != "":
+		return p.Location
+	case p.Origin == OriginFlag:
+		return "flag --" + p.Flag
+	case p.Origin == OriginEnv:
+		return "env " + p.EnvVar
+	case p.Origin == OriginDefault || p.Origin == OriginFlagDefault || p.Origin == "":
+		return ""
+	default:
+		return p.Origin
+	}
+}
+
+// fileLocation returns "path:line" for a key set by the layer, or "" if unknown.
+func (lyr layer) fileLocation(key string) string {
+	if lyr.path == "" {
+		return ""
+	}
+	if line, ok := lyr.lines[key]; ok {
+		return lyr.path + ":" + strconv.Itoa(line)
+	}
+	return lyr.path
+}
@@ -0,0 +1,135 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+	"github.com/spf13/pflag"
+)
+
+func TestOverlaysOverrideBaseFile(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	local := filepath.Join(dir, "config.local.yaml")
+	writeConfigFile(t, base, "server:\n  port: 8080\n  host: base-host\nlog_level: info\n")
+	writeConfigFile(t, local, "server:\n  host: local-host\n")
+
+	loader := config.NewLoader(config.WithFiles(base, local, filepath.Join(dir, "missing.yaml")))
+	cfg := &Config{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if cfg.Server.Port != 8080 || cfg.Server.Host != "local-host" || cfg.LogLevel != "info" {
+		t.Errorf("Unexpected merged configuration: %+v", cfg)
+	}
+}
+
+func TestProfileSelection(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, base, "server:\n  port: 8080\nlog_level: info\n")
+	writeConfigFile(t, filepath.Join(dir, "config.staging.yaml"), "log_level: debug\n")
+	writeConfigFile(t, filepath.Join(dir, "config.production.yaml"), "log_level: error\n")
+
+	t.Run("default profile", func(t *testing.T) {
+		loader := config.NewLoader(config.WithFile(base), config.WithProfile("staging"))
+		cfg := &Config{}
+		if err := loader.Load(cfg); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if cfg.LogLevel != "debug" || loader.Profile() != "staging" {
+			t.Errorf("Expected staging profile to apply, got log_level=%s profile=%s", cfg.LogLevel, loader.Profile())
+		}
+	})
+
+	t.Run("env selects profile", func(t *testing.T) {
+		setEnv(t, "MYAPP_PROFILE", "production")
+		defer unsetEnv(t, "MYAPP_PROFILE")
+
+		loader := config.NewLoader(config.WithFile(base), config.WithEnvPrefix("MYAPP"), config.WithProfile("staging"))
+		cfg := &Config{}
+		if err := loader.Load(cfg); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if cfg.LogLevel != "error" {
+			t.Errorf("Expected production profile to apply, got %s", cfg.LogLevel)
+		}
+	})
+
+	t.Run("flag selects profile", func(t *testing.T) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.String("profile", "", "Configuration profile")
+		flags.Int("server.port", 8080, "Server port")
+		flags.String("server.host", "", "Server host")
+		flags.String("log_level", "", "Log level")
+		flags.Parse([]string{"--profile=production"})
+
+		loader := config.NewLoader(config.WithFile(base), config.WithFlags(flags))
+		cfg := &Config{}
+		if err := loader.Load(cfg); err != nil {
+			t.Fatalf("Failed to load configuration: %v", err)
+		}
+		if cfg.LogLevel != "error" {
+			t.Errorf("Expected production profile to apply, got %s", cfg.LogLevel)
+		}
+	})
+}
+
+func TestIncludes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shared"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, filepath.Join(dir, "shared", "server.yaml"), "server:\n  port: 7000\n  host: shared-host\n")
+	writeConfigFile(t, base, "include:\n  - shared/server.yaml\nserver:\n  host: own-host\n")
+
+	loader := config.NewLoader(config.WithFile(base))
+	cfg := &Config{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 7000 || cfg.Server.Host != "own-host" {
+		t.Errorf("Unexpected configuration with includes: %+v", cfg.Server)
+	}
+	if loader.Get("include") != nil {
+		t.Errorf("Expected include directive to be removed, got %v", loader.Get("include"))
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, filepath.Join(dir, "a.yaml"), "include: b.yaml\n")
+	writeConfigFile(t, filepath.Join(dir, "b.yaml"), "include: a.yaml\n")
+
+	err := config.NewLoader(config.WithFile(filepath.Join(dir, "a.yaml"))).Load(&Config{})
+	if err == nil || !strings.Contains(err.Error(), "include cycle") {
+		t.Errorf("Expected an include cycle error, got %v", err)
+	}
+}
+
+func TestErrorsPointToFileAndLine(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	local := filepath.Join(dir, "config.local.yaml")
+	writeConfigFile(t, base, "server:\n  port: 8080\n")
+	writeConfigFile(t, local, "log_level: debug\nserver:\n  port: 0\n")
+
+	err := config.NewLoader(config.WithFiles(base, local)).Load(&Config{})
+	if err == nil {
+		t.Fatal("Expected a validation error")
+	}
+	if !strings.Contains(err.Error(), "server.port: is required (set at "+local+":3)") {
+		t.Errorf("Expected error to point to %s:3, got: %v", local, err)
+	}
+
+	writeConfigFile(t, local, "server:\n  port: not-a-number\n")
+	err = config.NewLoader(config.WithFiles(base, local)).Load(&Config{})
+	if err == nil || !strings.Contains(err.Error(), local+":2") {
+		t.Errorf("Expected decoding error to point to %s:2, got: %v", local, err)
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/mcuadros/go-defaults"
+	"github.com/spf13/pflag"
+)
+
+// RegisterFlags registers a flag on flags for every `cmdx`-tagged field of config, so that
+// commands stay in sync with the configuration struct. Flag names follow the `cmdx` tags
+// (nested structs are joined with "."), defaults follow `default` tags and usage strings
+// follow `desc` tags. Flags that are already registered are left untouched.
+//
+// The resulting flag set can be passed to `WithFlags`:
+//
+//	if err := config.RegisterFlags(cmd.Flags(), &Config{}); err != nil {
+//	    return err
+//	}
+//	loader := config.NewLoader(config.WithFlags(cmd.Flags()))
+func RegisterFlags(flags *pflag.FlagSet, config interface{}) error {
+	if err := validateStructPtr(config); err != nil {
+		return err
+	}
+
+	// Defaults are taken from a fresh copy so that the caller's struct is not modified.
+	value := reflect.New(reflect.TypeOf(config).Elem())
+	defaults.SetDefaults(value.Interface())
+	return registerFlags(flags, value.Elem(), "")
+}
+
+func registerFlags(flags *pflag.FlagSet, v reflect.Value, parentKey string) error {
+	structType := v.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("cmdx")
+		if tag == "" || !field.IsExported() {
+			continue
+		}
+
+		if parentKey != "" {
+			tag = parentKey + "." + tag
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			// Recurse into nested structs
+			if err := registerFlags(flags, v.Field(i), tag); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if flags.Lookup(tag) != nil {
+			continue
+		}
+		if err := registerFlag(flags, tag, field.Tag.Get("desc"), v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerFlag registers a single flag whose type and default follow value.
+func registerFlag(flags *pflag.FlagSet, name, usage string, value reflect.Value) error {
+	switch value.Type() {
+	case durationType:
+		flags.Duration(name, time.Duration(value.Int()), usage)
+		return nil
+	case reflect.TypeOf([]time.Duration(nil)):
+		flags.DurationSlice(name, value.Interface().([]time.Duration), usage)
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		flags.String(name, value.String(), usage)
+	case reflect.Bool:
+		flags.Bool(name, value.Bool(), usage)
+	case reflect.Int:
+		flags.Int(name, int(value.Int()), usage)
+	case reflect.Int8:
+		flags.Int8(name, int8(value.Int()), usage)
+	case reflect.Int16:
+		flags.Int16(name, int16(value.Int()), usage)
+	case reflect.Int32:
+		flags.Int32(name, int32(value.Int()), usage)
+	case reflect.Int64:
+		flags.Int64(name, value.Int(), usage)
+	case reflect.Uint:
+		flags.Uint(name, uint(value.Uint()), usage)
+	case reflect.Uint8:
+		flags.Uint8(name, uint8(value.Uint()), usage)
+	case reflect.Uint16:
+		flags.Uint16(name, uint16(value.Uint()), usage)
+	case reflect.Uint32:
+		flags.Uint32(name, uint32(value.Uint()), usage)
+	case reflect.Uint64:
+		flags.Uint64(name, value.Uint(), usage)
+	case reflect.Float32:
+		flags.Float32(name, float32(value.Float()), usage)
+	case reflect.Float64:
+		flags.Float64(name, value.Float(), usage)
+	case reflect.Slice:
+		return registerSliceFlag(flags, name, usage, value)
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String || value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s for flag: %s", value.Type(), name)
+		}
+		defaults := make(map[string]string, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			defaults[iter.Key().String()] = iter.Value().String()
+		}
+		flags.StringToString(name, defaults, usage)
+	default:
+		return fmt.Errorf("unsupported type %s for flag: %s", value.Type(), name)
+	}
+	return nil
+}
+
+func registerSliceFlag(flags *pflag.FlagSet, name, usage string, value reflect.Value) error {
+	switch value.Type().Elem().Kind() {
+	case reflect.String:
+		defaults := make([]string, value.Len())
+		for i := range defaults {
+			defaults[i] = value.Index(i).String()
+		}
+		flags.StringSlice(name, defaults, usage)
+	case reflect.Int:
+		defaults := make([]int, value.Len())
+		for i := range defaults {
+			defaults[i] = int(value.Index(i).Int())
+		}
+		flags.IntSlice(name, defaults, usage)
+	case reflect.Int64:
+		defaults := make([]int64, value.Len())
+		for i := range defaults {
+			defaults[i] = value.Index(i).Int()
+		}
+		flags.Int64Slice(name, defaults, usage)
+	case reflect.Uint:
+		defaults := make([]uint, value.Len())
+		for i := range defaults {
+			defaults[i] = uint(value.Index(i).Uint())
+		}
+		flags.UintSlice(name, defaults, usage)
+	case reflect.Float64:
+		defaults := make([]float64, value.Len())
+		for i := range defaults {
+			defaults[i] = value.Index(i).Float()
+		}
+		flags.Float64Slice(name, defaults, usage)
+	case reflect.Bool:
+		defaults := make([]bool, value.Len())
+		for i := range defaults {
+			defaults[i] = value.Index(i).Bool()
+		}
+		flags.BoolSlice(name, defaults, usage)
+	default:
+		return fmt.Errorf("unsupported type %s for flag: %s", value.Type(), name)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package config_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/raystack/salt/config"
+	"github.com/spf13/pflag"
+)
+
+type FlagConfig struct {
+	Server struct {
+		Port    int           `mapstructure:"port" cmdx:"port" default:"8080" desc:"Port to listen on"`
+		Timeout time.Duration `mapstructure:"timeout" cmdx:"timeout" default:"5s" desc:"Request timeout"`
+	} `mapstructure:"server" cmdx:"server"`
+	Tags     []string          `mapstructure:"tags" cmdx:"tags" default:"[a,b]" desc:"Tags to attach"`
+	Ports    []int             `mapstructure:"ports" cmdx:"ports"`
+	Labels   map[string]string `mapstructure:"labels" cmdx:"labels"`
+	Debug    bool              `mapstructure:"debug" cmdx:"debug"`
+	Password config.Secret     `mapstructure:"password" cmdx:"password"`
+	Internal string            `mapstructure:"internal"`
+}
+
+func TestRegisterFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := config.RegisterFlags(flags, &FlagConfig{}); err != nil {
+		t.Fatalf("Failed to register flags: %v", err)
+	}
+
+	port := flags.Lookup("server.port")
+	if port == nil || port.DefValue != "8080" || port.Usage != "Port to listen on" {
+		t.Fatalf("Unexpected server.port flag: %+v", port)
+	}
+	if timeout := flags.Lookup("server.timeout"); timeout == nil || timeout.DefValue != "5s" {
+		t.Errorf("Unexpected server.timeout flag: %+v", timeout)
+	}
+	if flags.Lookup("internal") != nil {
+		t.Error("Expected fields without cmdx tags to be skipped")
+	}
+
+	if err := flags.Parse([]string{
+		"--server.timeout=1m", "--tags=x", "--tags=y", "--ports=1,2", "--labels=team=core", "--debug", "--password=hunter2",
+	}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	cfg := &FlagConfig{}
+	if err := config.NewLoader(config.WithFlags(flags)).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if cfg.Server.Port != 8080 || cfg.Server.Timeout != time.Minute || !cfg.Debug || cfg.Password.Value() != "hunter2" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Tags, []string{"x", "y"}) || !reflect.DeepEqual(cfg.Ports, []int{1, 2}) {
+		t.Errorf("Unexpected slices: tags=%v ports=%v", cfg.Tags, cfg.Ports)
+	}
+	if cfg.Labels["team"] != "core" {
+		t.Errorf("Unexpected labels: %v", cfg.Labels)
+	}
+}
+
+func TestRegisterFlagsKeepsExistingFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.IntP("server.port", "p", 9000, "Custom port")
+
+	if err := config.RegisterFlags(flags, &FlagConfig{}); err != nil {
+		t.Fatalf("Failed to register flags: %v", err)
+	}
+	if port := flags.Lookup("server.port"); port.Shorthand != "p" || port.DefValue != "9000" {
+		t.Errorf("Expected hand-registered flag to be kept, got %+v", port)
+	}
+}
+
+func TestRegisterFlagsUnsupportedType(t *testing.T) {
+	type BadConfig struct {
+		Ch chan int `cmdx:"ch"`
+	}
+	if err := config.RegisterFlags(pflag.NewFlagSet("test", pflag.ContinueOnError), &BadConfig{}); err == nil {
+		t.Error("Expected an error for an unsupported field type")
+	}
+}
@@ -0,0 +1,343 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/printer"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/subosito/gotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Configuration file formats, detected from the file extension.
+const (
+	formatYAML   = "yaml"
+	formatJSON   = "json"
+	formatTOML   = "toml"
+	formatHCL    = "hcl"
+	formatDotenv = "dotenv"
+)
+
+// fileFormat returns the format of a configuration file from its extension.
+// Files with an unknown or no extension are read as YAML.
+func fileFormat(path string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	switch {
+	case ext == "json":
+		return formatJSON
+	case ext == "toml":
+		return formatTOML
+	case ext == "hcl" || ext == "tf":
+		return formatHCL
+	case ext == "env" || strings.HasPrefix(filepath.Base(path), ".env"):
+		return formatDotenv
+	default:
+		return formatYAML
+	}
+}
+
+// decodeConfig parses the content of a configuration file into nested values and the line of
+// every leaf value, keyed by lowercase dotted key. Lines are only known for YAML and dotenv files.
+func (l *Loader) decodeConfig(format string, content []byte) (map[string]interface{}, map[string]int, error) {
+	values := make(map[string]interface{})
+	lines := make(map[string]int)
+
+	switch format {
+	case formatJSON:
+		if err := json.Unmarshal(content, &values); err != nil {
+			return nil, nil, err
+		}
+	case formatTOML:
+		if err := toml.Unmarshal(content, &values); err != nil {
+			return nil, nil, err
+		}
+	case formatHCL:
+		if err := hcl.Unmarshal(content, &values); err != nil {
+			return nil, nil, err
+		}
+		values = hclBlocks(values)
+	case formatDotenv:
+		return l.decodeDotenv(content)
+	default:
+		var doc yaml.Node
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return nil, nil, err
+		}
+		if err := doc.Decode(&values); err != nil {
+			return nil, nil, err
+		}
+		if len(doc.Content) > 0 {
+			collectLines(doc.Content[0], "", lines)
+		}
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	return values, lines, nil
+}
+
+// hclBlocks replaces the single-element lists HCL decodes blocks into with the block itself,
+// so that `server { port = 8080 }` reads like `server: {port: 8080}`.
+func hclBlocks(values map[string]interface{}) map[string]interface{} {
+	for key, value := range values {
+		if blocks, ok := value.([]map[string]interface{}); ok && len(blocks) == 1 {
+			values[key] = hclBlocks(blocks[0])
+		}
+	}
+	return values
+}
+
+// decodeDotenv parses environment variable assignments. Variable names are mapped back to
+// configuration keys using the names `Load` binds, e.g. MYAPP_SERVER_PORT to "server.port".
+func (l *Loader) decodeDotenv(content []byte) (map[string]interface{}, map[string]int, error) {
+	env, err := gotenv.StrictParse(bytes.NewReader(content))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keys := make(map[string]string, len(l.defaultValues))
+	for key := range l.defaultValues {
+		key = strings.ToLower(key)
+		keys[l.envVarName(key)] = key
+	}
+	prefix := ""
+	if p := l.v.GetEnvPrefix(); p != "" {
+		prefix = strings.ToUpper(p) + "_"
+	}
+	keyFor := func(name string) string {
+		name = strings.ToUpper(name)
+		if key, ok := keys[name]; ok {
+			return key
+		}
+		return strings.ToLower(strings.TrimPrefix(name, prefix))
+	}
+
+	flat := make(map[string]interface{}, len(env))
+	for name, value := range env {
+		flat[keyFor(name)] = value
+	}
+
+	lines := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimPrefix(strings.TrimSpace(scanner.Text()), "export ")
+		if name, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+			if key := keyFor(strings.TrimSpace(name)); flat[key] != nil {
+				lines[key] = n
+			}
+		}
+	}
+	return unflattenMap(flat), lines, nil
+}
+
+// encodeConfig renders nested settings in the given format. YAML is merged into existing, the
+// current content of the file, so that its comments and key order are preserved.
+func (l *Loader) encodeConfig(format string, settings map[string]interface{}, existing []byte) ([]byte, error) {
+	settings = encodableSettings(settings)
+
+	switch format {
+	case formatJSON:
+		data, err := json.MarshalIndent(settings, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case formatTOML:
+		return toml.Marshal(settings)
+	case formatHCL:
+		data, err := json.Marshal(settings)
+		if err != nil {
+			return nil, err
+		}
+		ast, err := hcl.Parse(string(data))
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := printer.Fprint(&buf, ast.Node); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		return buf.Bytes(), nil
+	case formatDotenv:
+		return l.encodeDotenv(settings), nil
+	default:
+		return encodeYAML(settings, existing)
+	}
+}
+
+// encodableSettings drops nil values, including nil pointers of unset optional sections,
+// which TOML, HCL and dotenv cannot represent, and renders durations as strings such as "30s".
+func encodableSettings(settings map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			continue
+		}
+		switch v := value.(type) {
+		case nil:
+			continue
+		case map[string]interface{}:
+			out[key] = encodableSettings(v)
+		case time.Duration:
+			out[key] = v.String()
+		default:
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// encodeDotenv renders settings as sorted environment variable assignments.
+func (l *Loader) encodeDotenv(settings map[string]interface{}) []byte {
+	flat := flattenMap(settings)
+	names := make(map[string]string, len(flat))
+	for key := range flat {
+		names[l.envVarName(key)] = key
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	for _, name := range sorted {
+		fmt.Fprintf(&buf, "%s=%s\n", name, dotenvValue(flat[names[name]]))
+	}
+	return buf.Bytes()
+}
+
+func dotenvValue(value interface{}) string {
+	var s string
+	switch rv := reflect.ValueOf(value); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(rv.Index(i).Interface())
+		}
+		s = strings.Join(items, ",")
+	case reflect.Map:
+		// Empty maps carry no value.
+		return ""
+	default:
+		s = fmt.Sprint(value)
+	}
+	switch {
+	case strings.ContainsAny(s, "'\n"):
+		return strconv.Quote(s)
+	case s == "" || strings.ContainsAny(s, " \t\"#$\\"):
+		// Single-quoted values are not expanded, so secret references survive as written.
+		return "'" + s + "'"
+	default:
+		return s
+	}
+}
+
+// encodeYAML merges settings into the YAML document existing, keeping the comments, order and
+// style of keys that are still present. Keys missing from settings are removed, except for the
+// include directive, and new keys are appended in sorted order.
+func encodeYAML(settings map[string]interface{}, existing []byte) ([]byte, error) {
+	var doc yaml.Node
+	if len(bytes.TrimSpace(existing)) > 0 {
+		if err := yaml.Unmarshal(existing, &doc); err != nil {
+			return nil, err
+		}
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	if err := mergeYAMLMapping(doc.Content[0], settings, true); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mergeYAMLMapping(node *yaml.Node, settings map[string]interface{}, root bool) error {
+	// Settings keys are lowercase, file keys may not be.
+	byLower := make(map[string]string, len(settings))
+	for key := range settings {
+		byLower[strings.ToLower(key)] = key
+	}
+
+	seen := make(map[string]bool, len(settings))
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key, ok := byLower[strings.ToLower(keyNode.Value)]
+		if !ok {
+			if root && keyNode.Value == includeKey {
+				content = append(content, keyNode, valueNode)
+			}
+			continue
+		}
+		seen[key] = true
+
+		value := settings[key]
+		if nested, ok := value.(map[string]interface{}); ok && valueNode.Kind == yaml.MappingNode {
+			if err := mergeYAMLMapping(valueNode, nested, false); err != nil {
+				return err
+			}
+		} else if err := setYAMLValue(valueNode, value); err != nil {
+			return err
+		}
+		content = append(content, keyNode, valueNode)
+	}
+
+	added := make([]string, 0, len(settings))
+	for key := range settings {
+		if !seen[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	for _, key := range added {
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(settings[key]); err != nil {
+			return err
+		}
+		content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, valueNode)
+	}
+
+	node.Content = content
+	return nil
+}
+
+// setYAMLValue replaces the value of node, keeping its comments. Nodes that already hold an
+// equal value are left untouched to preserve their style.
+func setYAMLValue(node *yaml.Node, value interface{}) error {
+	var current interface{}
+	if err := node.Decode(&current); err == nil && reflect.DeepEqual(current, value) {
+		return nil
+	}
+
+	replacement := &yaml.Node{}
+	if err := replacement.Encode(value); err != nil {
+		return err
+	}
+	replacement.HeadComment = node.HeadComment
+	replacement.LineComment = node.LineComment
+	replacement.FootComment = node.FootComment
+	*node = *replacement
+	return nil
+}
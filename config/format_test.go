@@ -0,0 +1,167 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raystack/salt/config"
+)
+
+func TestLoadFileFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", "server:\n  port: 9090\n  host: example.com\nlog_level: debug\n"},
+		{"config.json", `{"server": {"port": 9090, "host": "example.com"}, "log_level": "debug"}`},
+		{"config.toml", "log_level = \"debug\"\n\n[server]\nport = 9090\nhost = \"example.com\"\n"},
+		{"config.hcl", "log_level = \"debug\"\n\nserver {\n  port = 9090\n  host = \"example.com\"\n}\n"},
+		{".env", "# deployment settings\nMYAPP_SERVER_PORT=9090\nexport MYAPP_SERVER_HOST=example.com\nMYAPP_LOG_LEVEL=debug\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			writeConfigFile(t, path, tt.content)
+
+			cfg := &Config{}
+			loader := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP"))
+			if err := loader.Load(cfg); err != nil {
+				t.Fatalf("Failed to load configuration: %v", err)
+			}
+			if cfg.Server.Port != 9090 || cfg.Server.Host != "example.com" || cfg.LogLevel != "debug" {
+				t.Errorf("Unexpected configuration: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestDotenvLocation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	writeConfigFile(t, path, "MYAPP_LOG_LEVEL=debug\nMYAPP_SERVER_PORT=0\n")
+
+	err := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP")).Load(&Config{})
+	if err == nil || !strings.Contains(err.Error(), path+":2") {
+		t.Errorf("Expected error to point to %s:2, got: %v", path, err)
+	}
+}
+
+func TestSavePreservesYAMLComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, `# Application configuration
+log_level: info # one of debug, info, warn, error
+server:
+  # Port to listen on
+  port: 8080
+  host: localhost
+`)
+
+	loader := config.NewLoader(config.WithFile(path))
+	if err := loader.Load(&Config{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	loader.Set("server.port", 9090)
+	if err := loader.Save(); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	want := `# Application configuration
+log_level: info # one of debug, info, warn, error
+server:
+  # Port to listen on
+  port: 9090
+  host: localhost
+`
+	if string(content) != want {
+		t.Errorf("Unexpected saved file:\n%s\nwant:\n%s", content, want)
+	}
+}
+
+func TestSaveWritesOnlyBaseFileAndSetValues(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, path, "server:\n  port: 8080\n")
+	writeConfigFile(t, filepath.Join(dir, "config.local.yaml"), "server:\n  host: overlay-host\n")
+
+	setEnv(t, "LOG_LEVEL", "debug")
+	defer unsetEnv(t, "LOG_LEVEL")
+
+	loader := config.NewLoader(
+		config.WithFiles(path, filepath.Join(dir, "config.local.yaml")),
+		config.WithSource(&config.MapSource{Values: map[string]interface{}{"server.host": "source-host"}}),
+	)
+	if err := loader.Load(&Config{}); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	loader.Set("server.port", 9090)
+	if err := loader.Save(); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+	if want := "server:\n  port: 9090\n"; string(content) != want {
+		t.Errorf("Expected only base file and set values to be saved, got:\n%s\nwant:\n%s", content, want)
+	}
+}
+
+func TestInitAndSaveFileFormats(t *testing.T) {
+	type FormatConfig struct {
+		Server struct {
+			Port    int           `mapstructure:"port" default:"8080"`
+			Timeout time.Duration `mapstructure:"timeout" default:"5s"`
+		} `mapstructure:"server"`
+		Tags     []string `mapstructure:"tags" default:"[a,b]"`
+		Password string   `mapstructure:"password" secret:"true"`
+	}
+
+	for _, name := range []string{"config.yaml", "config.json", "config.toml", "config.hcl", "app.env"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			loader := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP"))
+			if err := loader.Init(&FormatConfig{}); err != nil {
+				t.Fatalf("Failed to initialize configuration: %v", err)
+			}
+
+			setEnv(t, "MYAPP_PASSWORD", "hunter2")
+			defer unsetEnv(t, "MYAPP_PASSWORD")
+
+			cfg := &FormatConfig{}
+			if err := loader.Load(cfg); err != nil {
+				t.Fatalf("Failed to load initialized configuration: %v", err)
+			}
+			if cfg.Server.Port != 8080 || cfg.Server.Timeout != 5*time.Second || strings.Join(cfg.Tags, ",") != "a,b" {
+				t.Errorf("Unexpected configuration: %+v", cfg)
+			}
+
+			loader.Set("server.port", 9090)
+			if err := loader.Save(); err != nil {
+				t.Fatalf("Failed to save configuration: %v", err)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read saved file: %v", err)
+			}
+			if strings.Contains(string(content), "hunter2") || !strings.Contains(string(content), "${MYAPP_PASSWORD}") {
+				t.Errorf("Expected secret to be saved as a reference, got:\n%s", content)
+			}
+
+			cfg = &FormatConfig{}
+			if err := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP")).Load(cfg); err != nil {
+				t.Fatalf("Failed to load saved configuration: %v", err)
+			}
+			if cfg.Server.Port != 9090 || cfg.Password != "hunter2" {
+				t.Errorf("Unexpected saved configuration: %+v", cfg)
+			}
+		})
+	}
+}
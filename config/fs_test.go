@@ -0,0 +1,78 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+	"github.com/spf13/afero"
+)
+
+func writeFsFile(t *testing.T, fs afero.Fs, path, content string) {
+	t.Helper()
+	if err := afero.WriteFile(fs, path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestLoaderWithInMemoryFs(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	writeFsFile(t, fs, "/etc/app/config.yaml", "include: shared.yaml\nlog_level: ${file:/run/secrets/level}\n")
+	writeFsFile(t, fs, "/etc/app/shared.yaml", "server:\n  port: 9090\n")
+	writeFsFile(t, fs, "/run/secrets/level", "debug\n")
+
+	loader := config.NewLoader(config.WithFs(fs), config.WithFile("/etc/app/config.yaml"))
+	cfg := &Config{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 9090 || cfg.LogLevel != "debug" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+
+	loader.Set("server.host", "example.com")
+	if err := loader.Save(); err != nil {
+		t.Fatalf("Failed to save configuration: %v", err)
+	}
+	content, err := afero.ReadFile(fs, "/etc/app/config.yaml")
+	if err != nil || !strings.Contains(string(content), "host: example.com") {
+		t.Errorf("Expected configuration to be saved to the in-memory filesystem, got %q, %v", content, err)
+	}
+	if _, err := os.Stat("/etc/app/config.yaml"); err == nil {
+		t.Error("Expected the OS filesystem to be untouched")
+	}
+
+	if _, err := config.Watch[Config](context.Background(), loader); err == nil {
+		t.Error("Expected watching an in-memory filesystem to fail")
+	}
+}
+
+func TestInitCreatesDirectoryLazily(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	path := filepath.Join("/home/user/.config/raystack", "app.yml")
+	loader := config.NewLoader(config.WithFs(fs), config.WithFile(path))
+
+	if exists, _ := afero.DirExists(fs, filepath.Dir(path)); exists {
+		t.Fatal("Expected no directory to be created before Init")
+	}
+	if err := loader.Init(&Config{}); err != nil {
+		t.Fatalf("Failed to initialize configuration: %v", err)
+	}
+	if exists, _ := afero.Exists(fs, path); !exists {
+		t.Error("Expected Init to create the configuration file and its directory")
+	}
+}
+
+func TestWithAppConfigHasNoSideEffects(t *testing.T) {
+	dir := t.TempDir()
+	setEnv(t, "RAYSTACK_CONFIG_DIR", dir)
+	defer unsetEnv(t, "RAYSTACK_CONFIG_DIR")
+
+	config.NewLoader(config.WithAppConfig("app"))
+	if _, err := os.Stat(filepath.Join(dir, "raystack")); !os.IsNotExist(err) {
+		t.Errorf("Expected WithAppConfig not to create directories, got %v", err)
+	}
+}
@@ -6,16 +6,21 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"runtime"
+	"strings"
 
+	"github.com/go-playground/validator"
 	"github.com/jeremywohl/flatten"
 	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/afero"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 // bindFlags dynamically binds flags to configuration fields based on `cmdx` tags.
-func bindFlags(v *viper.Viper, flagSet *pflag.FlagSet, structType reflect.Type, parentKey string) error {
+// The names of the bound flags are recorded in bound, keyed by configuration key.
+func bindFlags(v *viper.Viper, flagSet *pflag.FlagSet, structType reflect.Type, parentKey string, bound map[string]string) error {
 	for i := 0; i < structType.NumField(); i++ {
 		field := structType.Field(i)
 		tag := field.Tag.Get("cmdx")
@@ -29,22 +34,78 @@ func bindFlags(v *viper.Viper, flagSet *pflag.FlagSet, structType reflect.Type,
 
 		if field.Type.Kind() == reflect.Struct {
 			// Recurse into nested structs
-			if err := bindFlags(v, flagSet, field.Type, tag); err != nil {
+			if err := bindFlags(v, flagSet, field.Type, tag, bound); err != nil {
 				return err
 			}
 		} else {
 			flag := flagSet.Lookup(tag)
 			if flag == nil {
-				return fmt.Errorf("missing flag for tag: %s", tag)
+				return fmt.Errorf("missing flag for tag: %s (register it or use RegisterFlags)", tag)
 			}
 			if err := v.BindPFlag(tag, flag); err != nil {
 				return fmt.Errorf("failed to bind flag for tag: %s, error: %w", tag, err)
 			}
+			bound[strings.ToLower(tag)] = flag.Name
 		}
 	}
 	return nil
 }
 
+// newValidator returns a validator that reports field names using their `mapstructure` keys.
+func newValidator() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.Split(field.Tag.Get("mapstructure"), ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return validate
+}
+
+// namespaceKey converts a validator namespace such as "Config.server.port" into the
+// lowercase dotted configuration key "server.port".
+func namespaceKey(namespace string) string {
+	if _, key, ok := strings.Cut(namespace, "."); ok {
+		return strings.ToLower(key)
+	}
+	return strings.ToLower(namespace)
+}
+
+var quotedKeyPattern = regexp.MustCompile(`'([^'\s]+)'`)
+
+// quotedKeys extracts the keys quoted in a decoding error message, e.g. "cannot parse 'server.port' as int".
+func quotedKeys(msg string) []string {
+	var keys []string
+	for _, match := range quotedKeyPattern.FindAllStringSubmatch(msg, -1) {
+		keys = append(keys, strings.ToLower(match[1]))
+	}
+	return keys
+}
+
+// locateKeys returns a suffix for error messages naming where the values of the keys were set.
+func (l *Loader) locateKeys(keys []string) string {
+	var locations []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if loc := l.location(key); loc != "" {
+			locations = append(locations, key+" set at "+loc)
+		}
+	}
+	if len(locations) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(locations, "; ") + ")"
+}
+
 // validateStructPtr ensures the provided value is a pointer to a struct.
 func validateStructPtr(value interface{}) error {
 	val := reflect.ValueOf(value)
@@ -56,11 +117,7 @@ func validateStructPtr(value interface{}) error {
 
 // extractFlattenedKeys retrieves all keys from the struct in a flattened format.
 func extractFlattenedKeys(config interface{}) ([]string, error) {
-	var structMap map[string]interface{}
-	if err := mapstructure.Decode(config, &structMap); err != nil {
-		return nil, err
-	}
-	flatMap, err := flatten.Flatten(structMap, "", flatten.DotStyle)
+	flatMap, err := flattenStruct(config)
 	if err != nil {
 		return nil, err
 	}
@@ -71,13 +128,58 @@ func extractFlattenedKeys(config interface{}) ([]string, error) {
 	return keys, nil
 }
 
-// Utilities for app-specific configuration paths
-func getConfigFilePath(app string) (string, error) {
-	dirPath := getConfigDir("raystack")
-	if err := ensureDir(dirPath); err != nil {
-		return "", err
+// flattenStruct decodes the struct into a map keyed by dotted configuration keys.
+func flattenStruct(config interface{}) (map[string]interface{}, error) {
+	var structMap map[string]interface{}
+	if err := mapstructure.Decode(config, &structMap); err != nil {
+		return nil, err
+	}
+	return flatten.Flatten(structMap, "", flatten.DotStyle)
+}
+
+// flattenMap converts nested maps into a single map keyed by lowercase dotted keys.
+func flattenMap(m map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	flattenInto(flat, "", m)
+	return flat
+}
+
+func flattenInto(flat map[string]interface{}, prefix string, m map[string]interface{}) {
+	for k, v := range m {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+			flattenInto(flat, key, nested)
+			continue
+		}
+		flat[key] = v
+	}
+}
+
+// unflattenMap converts a map keyed by dotted keys into nested maps.
+func unflattenMap(flat map[string]interface{}) map[string]interface{} {
+	nested := make(map[string]interface{})
+	for key, value := range flat {
+		parts := strings.Split(key, ".")
+		m := nested
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				m[part] = child
+			}
+			m = child
+		}
+		m[parts[len(parts)-1]] = value
 	}
-	return filepath.Join(dirPath, app+".yml"), nil
+	return nested
+}
+
+// Utilities for app-specific configuration paths
+func getConfigFilePath(app string) string {
+	return filepath.Join(getConfigDir("raystack"), app+".yml")
 }
 
 func getConfigDir(root string) string {
@@ -94,18 +196,13 @@ func getConfigDir(root string) string {
 	}
 }
 
-func ensureDir(dir string) error {
-	if err := os.MkdirAll(dir, 0755); err != nil {
+func ensureDir(fs afero.Fs, dir string) error {
+	if err := fs.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 	return nil
 }
 
-func fileExists(filename string) bool {
-	_, err := os.Stat(filename)
-	return err == nil
-}
-
 func envSet(key string) bool {
 	return os.Getenv(key) != ""
 }
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// Placeholder prefixes recognised inside `${...}`.
+const (
+	filePlaceholder = "file:"
+	refPlaceholder  = "ref:"
+)
+
+// InterpolationError reports a placeholder in a configuration file that could not be resolved.
+type InterpolationError struct {
+	Key         string // Dotted key whose value contains the placeholder.
+	Placeholder string // The placeholder, e.g. "${DB_PASSWORD}".
+	Reason      string
+}
+
+func (e *InterpolationError) Error() string {
+	return fmt.Sprintf("%s: cannot resolve %s: %s", e.Key, e.Placeholder, e.Reason)
+}
+
+// interpolator resolves placeholders in flattened configuration values:
+//
+//	${NAME}          the environment variable NAME
+//	${NAME:-value}   the environment variable NAME, or value if it is unset or empty
+//	${file:path}     the trimmed content of the file at path
+//	${ref:key}       the (interpolated) value of another configuration key
+//	$${              a literal "${"
+type interpolator struct {
+	fs       afero.Fs
+	values   map[string]interface{}
+	resolved map[string]interface{}
+	errs     map[string]*InterpolationError
+	stack    []string
+}
+
+// interpolate resolves placeholders in the values in place. Values with unresolvable
+// placeholders are removed and reported.
+func interpolate(values map[string]interface{}, fs afero.Fs) []*InterpolationError {
+	in := &interpolator{
+		fs:       fs,
+		values:   values,
+		resolved: make(map[string]interface{}),
+		errs:     make(map[string]*InterpolationError),
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []*InterpolationError
+	for _, key := range keys {
+		value, err := in.resolveKey(key)
+		if err != nil {
+			delete(values, key)
+			errs = append(errs, err)
+			continue
+		}
+		values[key] = value
+	}
+	return errs
+}
+
+func (in *interpolator) resolveKey(key string) (interface{}, *InterpolationError) {
+	if value, ok := in.resolved[key]; ok {
+		return value, nil
+	}
+	if err, ok := in.errs[key]; ok {
+		return nil, err
+	}
+
+	in.stack = append(in.stack, key)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	value, err := in.resolveValue(key, in.values[key])
+	if err != nil {
+		in.errs[key] = err
+		return nil, err
+	}
+	in.resolved[key] = value
+	return value, nil
+}
+
+func (in *interpolator) resolveValue(key string, value interface{}) (interface{}, *InterpolationError) {
+	switch v := value.(type) {
+	case string:
+		return in.expand(key, v)
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := in.resolveValue(key, item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = resolved
+		}
+		return items, nil
+	default:
+		return value, nil
+	}
+}
+
+// expand replaces every placeholder in s.
+func (in *interpolator) expand(key, s string) (string, *InterpolationError) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := matchingBrace(s, start+2)
+		if end < 0 {
+			return "", &InterpolationError{Key: key, Placeholder: s[start:], Reason: "missing closing brace"}
+		}
+		placeholder := s[start : end+1]
+		value, err := in.resolvePlaceholder(key, placeholder, s[start+2:end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s[:start] + value)
+		s = s[end+1:]
+	}
+}
+
+func (in *interpolator) resolvePlaceholder(key, placeholder, expr string) (string, *InterpolationError) {
+	switch {
+	case strings.HasPrefix(expr, filePlaceholder):
+		path := strings.TrimPrefix(expr, filePlaceholder)
+		content, err := afero.ReadFile(in.fs, path)
+		if err != nil {
+			return "", &InterpolationError{Key: key, Placeholder: placeholder, Reason: err.Error()}
+		}
+		return strings.TrimSpace(string(content)), nil
+
+	case strings.HasPrefix(expr, refPlaceholder):
+		ref := strings.ToLower(strings.TrimPrefix(expr, refPlaceholder))
+		for _, k := range in.stack {
+			if k == ref {
+				cycle := strings.Join(append(in.stack, ref), " -> ")
+				return "", &InterpolationError{Key: key, Placeholder: placeholder, Reason: "reference cycle " + cycle}
+			}
+		}
+		if _, ok := in.values[ref]; !ok {
+			return "", &InterpolationError{Key: key, Placeholder: placeholder, Reason: fmt.Sprintf("key %q is not set", ref)}
+		}
+		value, err := in.resolveKey(ref)
+		if err != nil {
+			return "", &InterpolationError{Key: key, Placeholder: placeholder, Reason: err.Reason}
+		}
+		return fmt.Sprint(value), nil
+
+	default:
+		name, fallback, hasFallback := strings.Cut(expr, ":-")
+		if value := os.Getenv(name); value != "" {
+			return value, nil
+		}
+		if hasFallback {
+			return in.expand(key, fallback)
+		}
+		return "", &InterpolationError{Key: key, Placeholder: placeholder, Reason: fmt.Sprintf("environment variable %s is not set", name)}
+	}
+}
+
+// matchingBrace returns the index of the brace closing a placeholder whose content starts at
+// from, allowing nested placeholders in defaults. It returns -1 if there is none.
+func matchingBrace(s string, from int) int {
+	depth := 1
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '{' && i > 0 && s[i-1] == '$':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/afero"
+)
+
+// versionKey is the top-level key recording the version of a configuration file.
+const versionKey = "version"
+
+// Migration upgrades the raw values of a configuration file to Version.
+// Migrate receives the nested values as read from the file and modifies them in place.
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(values map[string]interface{}) error
+}
+
+// MigrationReport describes the migration of a configuration file.
+type MigrationReport struct {
+	From    int      // Version of the file before migrating.
+	To      int      // Version of the file after migrating.
+	Applied []string // Descriptions of the applied migrations, in order.
+	Diff    string   // Unified diff of the file contents.
+	Backup  string   // Path of the backup of the original file, empty for dry runs.
+}
+
+// MigrateOption customizes `Migrate`.
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	dryRun bool
+}
+
+// WithDryRun makes `Migrate` report the migration and its diff without writing any file.
+func WithDryRun() MigrateOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+// WithMigrations registers migrations for the base configuration file. The version of the
+// file is read from its top-level `version` key (0 when missing); migrations with a higher
+// version are applied in order. `Load` applies them in memory, so that outdated files keep
+// working, and `Migrate` rewrites the file.
+//
+// Example:
+//
+//	loader := config.NewLoader(
+//	    config.WithAppConfig("myapp"),
+//	    config.WithMigrations(config.Migration{
+//	        Version:     1,
+//	        Description: "rename log.level to log_level",
+//	        Migrate: func(values map[string]interface{}) error {
+//	            config.MoveKey(values, "log.level", "log_level")
+//	            return nil
+//	        },
+//	    }),
+//	)
+func WithMigrations(migrations ...Migration) Option {
+	return func(l *Loader) {
+		l.migrations = append(l.migrations, migrations...)
+	}
+}
+
+// MoveKey moves the value at the dotted key from to the dotted key to within nested values,
+// removing parent maps left empty. It reports whether from was set.
+func MoveKey(values map[string]interface{}, from, to string) bool {
+	value, ok := deleteKey(values, strings.Split(from, "."))
+	if !ok {
+		return false
+	}
+	m := values
+	parts := strings.Split(to, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := m[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			m[part] = child
+		}
+		m = child
+	}
+	m[parts[len(parts)-1]] = value
+	return true
+}
+
+func deleteKey(values map[string]interface{}, parts []string) (interface{}, bool) {
+	if len(parts) == 1 {
+		value, ok := values[parts[0]]
+		delete(values, parts[0])
+		return value, ok
+	}
+	child, ok := values[parts[0]].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	value, ok := deleteKey(child, parts[1:])
+	if ok && len(child) == 0 {
+		delete(values, parts[0])
+	}
+	return value, ok
+}
+
+// Migrate upgrades the base configuration file to the latest registered migration version.
+// The original file is backed up to `<path>.v<version>.bak` before it is rewritten. A file
+// that is already up to date is left untouched.
+func (l *Loader) Migrate(opts ...MigrateOption) (*MigrationReport, error) {
+	var o migrateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	path := l.v.ConfigFileUsed()
+	if path == "" {
+		return nil, errors.New("no configuration file specified for migration")
+	}
+	content, err := afero.ReadFile(l.fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	format := fileFormat(path)
+	values, _, err := l.decodeConfig(format, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	report, err := l.migrate(values)
+	if err != nil {
+		return nil, err
+	}
+	if report.From == report.To {
+		return report, nil
+	}
+
+	migrated, err := l.encodeConfig(format, values, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal configuration: %w", err)
+	}
+	report.Diff, err = difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(content)),
+		B:        difflib.SplitLines(string(migrated)),
+		FromFile: fmt.Sprintf("%s (version %d)", path, report.From),
+		ToFile:   fmt.Sprintf("%s (version %d)", path, report.To),
+		Context:  3,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff configuration: %w", err)
+	}
+	if o.dryRun {
+		return report, nil
+	}
+
+	info, err := l.fs.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %w", err)
+	}
+	report.Backup = fmt.Sprintf("%s.v%d.bak", path, report.From)
+	if err := afero.WriteFile(l.fs, report.Backup, content, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to back up configuration file: %w", err)
+	}
+	if err := afero.WriteFile(l.fs, path, migrated, info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write configuration file: %w", err)
+	}
+	return report, nil
+}
+
+// migrate applies the pending migrations to values in place and sets their version.
+func (l *Loader) migrate(values map[string]interface{}) (*MigrationReport, error) {
+	migrations := make([]Migration, len(l.migrations))
+	copy(migrations, l.migrations)
+	sort.SliceStable(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	latest := l.latestVersion()
+
+	version, err := fileVersion(values)
+	if err != nil {
+		return nil, err
+	}
+	if version > latest {
+		return nil, fmt.Errorf("configuration file version %d is newer than the latest supported version %d", version, latest)
+	}
+
+	report := &MigrationReport{From: version, To: version}
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		if err := m.Migrate(values); err != nil {
+			return nil, fmt.Errorf("migration to version %d failed: %w", m.Version, err)
+		}
+		report.To = m.Version
+		report.Applied = append(report.Applied, m.Description)
+	}
+	if report.To != report.From {
+		values[versionKey] = report.To
+	}
+	return report, nil
+}
+
+// latestVersion returns the highest registered migration version, 0 if there are none.
+func (l *Loader) latestVersion() int {
+	latest := 0
+	for _, m := range l.migrations {
+		latest = max(latest, m.Version)
+	}
+	return latest
+}
+
+// fileVersion returns the version recorded in the values of a configuration file, 0 if none.
+func fileVersion(values map[string]interface{}) (int, error) {
+	value, ok := values[versionKey]
+	if !ok || value == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(fmt.Sprint(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid configuration file %s %v: expected an integer", versionKey, value)
+	}
+	return version, nil
+}
@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+)
+
+var testMigrations = []config.Migration{
+	{
+		Version:     1,
+		Description: "move port under server",
+		Migrate: func(values map[string]interface{}) error {
+			config.MoveKey(values, "port", "server.port")
+			return nil
+		},
+	},
+	{
+		Version:     2,
+		Description: "rename log.level to log_level",
+		Migrate: func(values map[string]interface{}) error {
+			config.MoveKey(values, "log.level", "log_level")
+			return nil
+		},
+	},
+}
+
+const legacyConfig = `# Legacy configuration
+
+port: 9090
+log:
+  level: debug
+`
+
+func TestLoadMigratesInMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, legacyConfig)
+
+	cfg := &Config{}
+	loader := config.NewLoader(config.WithFile(path), config.WithMigrations(testMigrations...))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 9090 || cfg.LogLevel != "debug" {
+		t.Errorf("Expected migrated values, got %+v", cfg)
+	}
+	if readFile(t, path) != legacyConfig {
+		t.Error("Expected Load not to rewrite the file")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, legacyConfig)
+	loader := config.NewLoader(config.WithFile(path), config.WithMigrations(testMigrations...))
+
+	report, err := loader.Migrate(config.WithDryRun())
+	if err != nil {
+		t.Fatalf("Failed to migrate configuration: %v", err)
+	}
+	if report.From != 0 || report.To != 2 || len(report.Applied) != 2 || report.Backup != "" {
+		t.Errorf("Unexpected dry run report: %+v", report)
+	}
+	for _, want := range []string{"-port: 9090", "+log_level: debug", "+version: 2"} {
+		if !strings.Contains(report.Diff, want) {
+			t.Errorf("Expected diff to contain %q, got:\n%s", want, report.Diff)
+		}
+	}
+	if readFile(t, path) != legacyConfig {
+		t.Fatal("Expected dry run not to modify the file")
+	}
+
+	report, err = loader.Migrate()
+	if err != nil {
+		t.Fatalf("Failed to migrate configuration: %v", err)
+	}
+	if report.Backup != path+".v0.bak" || readFile(t, report.Backup) != legacyConfig {
+		t.Errorf("Expected original file to be backed up, got %q", report.Backup)
+	}
+	want := "# Legacy configuration\n\nlog_level: debug\nserver:\n  port: 9090\nversion: 2\n"
+	if got := readFile(t, path); got != want {
+		t.Errorf("Unexpected migrated file:\n%s\nwant:\n%s", got, want)
+	}
+
+	report, err = loader.Migrate()
+	if err != nil || report.From != 2 || report.To != 2 || report.Diff != "" {
+		t.Errorf("Expected migrated file to be up to date, got %+v, %v", report, err)
+	}
+}
+
+func TestMigrateRejectsNewerFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "version: 3\n")
+
+	_, err := config.NewLoader(config.WithFile(path), config.WithMigrations(testMigrations...)).Migrate()
+	if err == nil || !strings.Contains(err.Error(), "newer than the latest supported version 2") {
+		t.Errorf("Expected an error for a newer file, got %v", err)
+	}
+}
+
+func TestInitWritesLatestVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	loader := config.NewLoader(config.WithFile(path), config.WithMigrations(testMigrations...))
+	if err := loader.Init(&Config{}); err != nil {
+		t.Fatalf("Failed to initialize configuration: %v", err)
+	}
+	if content := readFile(t, path); !strings.Contains(content, "\nversion: 2\n") {
+		t.Errorf("Expected initialized file to record the latest version, got:\n%s", content)
+	}
+	if _, err := os.Stat(path + ".v0.bak"); err == nil {
+		t.Error("Expected no backup for a new file")
+	}
+}
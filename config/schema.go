@@ -0,0 +1,328 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern matches values accepted by time.ParseDuration, e.g. "1h30m" or "100ms".
+const durationPattern = `^[-+]?([0-9]*(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$`
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// Schema is the subset of JSON Schema used to describe configuration files.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	MinProperties        *int               `json:"minProperties,omitempty"`
+	MaxProperties        *int               `json:"maxProperties,omitempty"`
+}
+
+// GenerateSchema builds a JSON Schema describing the configuration file for the given struct,
+// the same struct passed to `Load`.
+//
+// Property names follow `mapstructure` tags, defaults come from `default` tags, descriptions
+// from `desc` tags, and the `validate` rules required, min, max, len, gt, gte, lt, lte, oneof,
+// email, url and hostname are translated into schema constraints. Rules after `dive` apply to
+// the items of slices and maps.
+//
+// Example:
+//
+//	schema, err := config.GenerateSchema(&Config{})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	data, _ := json.MarshalIndent(schema, "", "  ")
+//	os.WriteFile("config.schema.json", data, 0644)
+func GenerateSchema(config interface{}) (*Schema, error) {
+	t := reflect.TypeOf(config)
+	if t == nil {
+		return nil, errors.New("schema generation requires a struct")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("schema generation requires a struct")
+	}
+
+	schema, err := typeSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	schema.Schema = schemaDraft
+	schema.Title = t.Name()
+	return schema, nil
+}
+
+// GenerateSchemaJSON returns the schema of GenerateSchema as indented JSON.
+func GenerateSchemaJSON(config interface{}) ([]byte, error) {
+	schema, err := GenerateSchema(config)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func typeSchema(t reflect.Type) (*Schema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == durationType:
+		return &Schema{Type: "string", Pattern: durationPattern}, nil
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+		if err := addStructProperties(schema, t); err != nil {
+			return nil, err
+		}
+		return schema, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func addStructProperties(schema *Schema, t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tagParts := strings.Split(field.Tag.Get("mapstructure"), ",")
+		name := tagParts[0]
+		if name == "-" {
+			continue
+		}
+		if slices.Contains(tagParts[1:], "squash") {
+			if err := addStructProperties(schema, field.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop, err := typeSchema(field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		prop.Description = field.Tag.Get("desc")
+		def, hasDefault := field.Tag.Lookup("default")
+		if hasDefault {
+			prop.Default = parseDefault(field.Type, def)
+		}
+		required, err := applyValidateRules(prop, field.Type, field.Tag.Get("validate"))
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		// Fields with a default may be left out of configuration files
+		if required && !hasDefault {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+	return nil
+}
+
+// applyValidateRules translates validator rules into schema constraints and reports
+// whether the field is required.
+func applyValidateRules(schema *Schema, t reflect.Type, tag string) (bool, error) {
+	if tag == "" {
+		return false, nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "dive":
+			if schema.Items != nil {
+				_, err := applyValidateRules(schema.Items, t.Elem(), strings.Join(rules[i+1:], ","))
+				return required, err
+			}
+			if schema.AdditionalProperties != nil {
+				_, err := applyValidateRules(schema.AdditionalProperties, t.Elem(), strings.Join(rules[i+1:], ","))
+				return required, err
+			}
+			return required, nil
+		case "min", "max", "len":
+			if err := applyBound(schema, t, name, param); err != nil {
+				return false, err
+			}
+		case "gt", "gte", "lt", "lte":
+			if t == durationType {
+				continue
+			}
+			n, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s rule %q: %w", name, param, err)
+			}
+			switch name {
+			case "gt":
+				schema.ExclusiveMinimum = &n
+			case "gte":
+				schema.Minimum = &n
+			case "lt":
+				schema.ExclusiveMaximum = &n
+			case "lte":
+				schema.Maximum = &n
+			}
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, parseDefault(t, value))
+			}
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "hostname":
+			schema.Format = "hostname"
+		}
+	}
+	return required, nil
+}
+
+// applyBound sets the length or range constraint matching the kind of the field.
+func applyBound(schema *Schema, t reflect.Type, rule, param string) error {
+	if t == durationType {
+		// Duration bounds cannot be expressed on the string representation.
+		return nil
+	}
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s rule %q: %w", rule, param, err)
+	}
+	count := int(n)
+
+	var lower, upper **int
+	switch t.Kind() {
+	case reflect.String:
+		lower, upper = &schema.MinLength, &schema.MaxLength
+	case reflect.Slice, reflect.Array:
+		lower, upper = &schema.MinItems, &schema.MaxItems
+	case reflect.Map:
+		lower, upper = &schema.MinProperties, &schema.MaxProperties
+	default:
+		switch rule {
+		case "min":
+			schema.Minimum = &n
+		case "max":
+			schema.Maximum = &n
+		case "len":
+			schema.Minimum, schema.Maximum = &n, &n
+		}
+		return nil
+	}
+
+	switch rule {
+	case "min":
+		*lower = &count
+	case "max":
+		*upper = &count
+	case "len":
+		*lower, *upper = &count, &count
+	}
+	return nil
+}
+
+// parseDefault converts a tag value into a JSON value of the field's type,
+// falling back to the raw string when it cannot be converted.
+func parseDefault(t reflect.Type, value string) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType {
+		return value
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
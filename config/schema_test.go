@@ -0,0 +1,94 @@
+package config_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/raystack/salt/config"
+)
+
+type SchemaConfig struct {
+	Server struct {
+		Port    int           `mapstructure:"port" default:"8080" validate:"required,min=1,max=65535" desc:"Port to listen on"`
+		Host    string        `mapstructure:"host" validate:"hostname"`
+		Timeout time.Duration `mapstructure:"timeout" default:"5s"`
+	} `mapstructure:"server"`
+	LogLevel string            `mapstructure:"log_level" default:"info" validate:"required,oneof=debug info warn error"`
+	Name     string            `mapstructure:"name" validate:"required"`
+	Tags     []string          `mapstructure:"tags" validate:"min=1,dive,min=3"`
+	Labels   map[string]string `mapstructure:"labels"`
+	Ratio    float64           `mapstructure:"ratio" validate:"gt=0,lte=1"`
+	internal string
+}
+
+func TestGenerateSchema(t *testing.T) {
+	schema, err := config.GenerateSchema(&SchemaConfig{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	if schema.Type != "object" || schema.Title != "SchemaConfig" || schema.Schema == "" {
+		t.Errorf("Unexpected root schema: %+v", schema)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"name"}) {
+		t.Errorf("Expected only name to be required, as log_level has a default, got %v", schema.Required)
+	}
+	if _, ok := schema.Properties["internal"]; ok {
+		t.Error("Expected unexported fields to be skipped")
+	}
+
+	server := schema.Properties["server"]
+	if len(server.Required) != 0 {
+		t.Errorf("Expected server.port not to be required, as it has a default, got %v", server.Required)
+	}
+	port := server.Properties["port"]
+	if port.Type != "integer" || port.Default != int64(8080) || *port.Minimum != 1 || *port.Maximum != 65535 || port.Description != "Port to listen on" {
+		t.Errorf("Unexpected schema for server.port: %+v", port)
+	}
+	if host := server.Properties["host"]; host.Format != "hostname" {
+		t.Errorf("Expected hostname format for server.host, got %+v", host)
+	}
+	if timeout := server.Properties["timeout"]; timeout.Type != "string" || timeout.Pattern == "" || timeout.Default != "5s" {
+		t.Errorf("Unexpected schema for server.timeout: %+v", timeout)
+	}
+
+	logLevel := schema.Properties["log_level"]
+	if !reflect.DeepEqual(logLevel.Enum, []interface{}{"debug", "info", "warn", "error"}) || logLevel.Default != "info" {
+		t.Errorf("Unexpected schema for log_level: %+v", logLevel)
+	}
+
+	tags := schema.Properties["tags"]
+	if tags.Type != "array" || *tags.MinItems != 1 || tags.Items.Type != "string" || *tags.Items.MinLength != 3 {
+		t.Errorf("Unexpected schema for tags: %+v", tags)
+	}
+	if labels := schema.Properties["labels"]; labels.Type != "object" || labels.AdditionalProperties.Type != "string" {
+		t.Errorf("Unexpected schema for labels: %+v", labels)
+	}
+	if ratio := schema.Properties["ratio"]; *ratio.ExclusiveMinimum != 0 || *ratio.Maximum != 1 {
+		t.Errorf("Unexpected schema for ratio: %+v", ratio)
+	}
+}
+
+func TestGenerateSchemaJSON(t *testing.T) {
+	data, err := config.GenerateSchemaJSON(&Config{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Generated schema is not valid JSON: %v", err)
+	}
+	port := doc["properties"].(map[string]interface{})["server"].(map[string]interface{})["properties"].(map[string]interface{})["port"].(map[string]interface{})
+	if port["default"] != float64(8000) || port["minimum"] != float64(1) {
+		t.Errorf("Unexpected schema for server.port: %v", port)
+	}
+}
+
+func TestGenerateSchemaRequiresStruct(t *testing.T) {
+	if _, err := config.GenerateSchema("not a struct"); err == nil {
+		t.Error("Expected an error for a non-struct value")
+	}
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Secret is a string that never reveals its value when formatted with the fmt package,
+// e.g. when a configuration struct is logged with "%+v". Use Value to read the plaintext.
+//
+// Fields of type Secret are treated as if they carried the `secret:"true"` tag.
+type Secret string
+
+// Value returns the plaintext value of the secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns a masked representation of the secret.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+// GoString returns a masked representation of the secret for the "%#v" verb.
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+// Format implements fmt.Formatter so that every verb prints the masked representation.
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch verb {
+	case 'q':
+		fmt.Fprintf(f, "%q", s.String())
+	default:
+		fmt.Fprint(f, s.String())
+	}
+}
+
+var secretType = reflect.TypeOf(Secret(""))
+
+// Redact returns the configuration struct as a nested map with the values of
+// secret fields masked, suitable for logging or printing.
+func Redact(config interface{}) (map[string]interface{}, error) {
+	values, err := flattenStruct(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten configuration: %w", err)
+	}
+	secrets := secretKeys(reflect.TypeOf(config))
+	for key, value := range values {
+		if secrets[strings.ToLower(key)] {
+			values[key] = mask(value)
+		}
+	}
+	return unflattenMap(values), nil
+}
+
+// secretKeys returns the lowercase dotted keys of all fields tagged with `secret:"true"`
+// or declared with the Secret type.
+func secretKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	collectSecretKeys(t, "", keys)
+	return keys
+}
+
+func collectSecretKeys(t reflect.Type, prefix string, keys map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		key := fieldKey(field, prefix)
+		if field.Tag.Get("secret") == "true" || field.Type == secretType {
+			keys[key] = true
+			continue
+		}
+		collectSecretKeys(field.Type, key, keys)
+	}
+}
+
+// fieldKey returns the lowercase dotted configuration key of a struct field,
+// following the same naming rules as mapstructure.
+func fieldKey(field reflect.StructField, prefix string) string {
+	name := field.Name
+	if tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]; tag != "" {
+		name = tag
+	}
+	name = strings.ToLower(name)
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// isSecret reports whether the value of the key must not be displayed or persisted in plaintext.
+func (l *Loader) isSecret(key string) bool {
+	return l.secretKeys[strings.ToLower(key)] || isSecretKey(key)
+}
+
+// redactSettings masks the values of secret keys in nested settings.
+func (l *Loader) redactSettings(settings map[string]interface{}) map[string]interface{} {
+	flat := flattenMap(settings)
+	for key, value := range flat {
+		if l.isSecret(key) {
+			flat[key] = mask(value)
+		}
+	}
+	return unflattenMap(flat)
+}
+
+// secretReferences replaces the values of secret fields supplied by environment variables in
+// flattened settings with references to them, e.g. "${MYAPP_DB_PASSWORD}", adding those missing
+// from the settings; other values are kept, so that a saved file loads again. Values decrypted by
+// the last Load are replaced with their ciphertext instead, as long as they are unchanged.
+func (l *Loader) secretReferences(flat map[string]interface{}) map[string]interface{} {
+	for key, value := range flat {
+		if enc, ok := l.encrypted[key]; ok && fmt.Sprint(value) == fmt.Sprint(enc.plaintext) {
+			flat[key] = enc.ciphertext
+		}
+	}
+	for key := range l.secretKeys {
+		if env := l.envVarName(key); os.Getenv(env) != "" && fmt.Sprint(l.v.Get(key)) == os.Getenv(env) {
+			flat[key] = "${" + env + "}"
+		}
+	}
+	return flat
+}
+
+// encryptSetSecrets encrypts the secrets among flattened settings that were set with Set, unless
+// they were already replaced with a reference or their ciphertext. Without an encryption key,
+// they cannot be saved.
+func (l *Loader) encryptSetSecrets(flat, overrides map[string]interface{}) error {
+	var key string
+	for name, value := range overrides {
+		name = strings.ToLower(name)
+		if !l.secretKeys[name] || fmt.Sprint(flat[name]) != fmt.Sprint(value) {
+			continue
+		}
+		if key == "" {
+			var err error
+			if key, err = l.resolveEncryptionKey(); err != nil {
+				return fmt.Errorf("cannot save secret %s in plaintext, supply it through %s or configure an encryption key: %w", name, l.envVarName(name), err)
+			}
+		}
+		ciphertext, err := Encrypt(key, fmt.Sprint(value))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", name, err)
+		}
+		flat[name] = ciphertext
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const defaultSourceTimeout = 30 * time.Second
+
+// Source supplies configuration values from a system other than the configuration file,
+// environment variables or flags, e.g. a secret store or a key/value service.
+//
+// Sources are merged on top of the configuration file in the order they are registered
+// with `WithSource`, so a later source overrides an earlier one. Environment variables
+// and flags still take precedence over every source.
+type Source interface {
+	// Name identifies the source in reports and error messages.
+	Name() string
+
+	// Load returns the configuration values provided by the source. Keys may either be
+	// nested maps or dotted keys such as "db.password".
+	Load(ctx context.Context) (map[string]interface{}, error)
+}
+
+// SourceReport lists the keys a source supplied during the last call to `Load`.
+type SourceReport struct {
+	Name string
+	Keys []string
+}
+
+// WithSource registers an additional configuration source.
+func WithSource(src Source) Option {
+	return func(l *Loader) {
+		l.sources = append(l.sources, src)
+	}
+}
+
+// WithSourceTimeout limits how long each source may take to load. Defaults to 30 seconds.
+func WithSourceTimeout(d time.Duration) Option {
+	return func(l *Loader) {
+		l.sourceTimeout = d
+	}
+}
+
+// Sources reports which keys each registered source supplied during the last call to `Load`.
+func (l *Loader) Sources() []SourceReport {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]SourceReport(nil), l.sourceReports...)
+}
+
+// mergeSources loads every registered source and merges its values on top of the configuration file.
+func (l *Loader) mergeSources() error {
+	reports := make([]SourceReport, 0, len(l.sources))
+	for _, src := range l.sources {
+		values, err := loadSource(src, l.sourceTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to load config source %q: %w", src.Name(), err)
+		}
+
+		lyr := layer{name: src.Name(), values: flattenMap(values)}
+		l.applyAliases(&lyr)
+		if err := l.decryptValues(lyr.values, []layer{lyr}); err != nil {
+			return fmt.Errorf("failed to load config source %q: %w", src.Name(), err)
+		}
+		keys := make([]string, 0, len(lyr.values))
+		for k := range lyr.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		if err := l.v.MergeConfigMap(unflattenMap(lyr.values)); err != nil {
+			return fmt.Errorf("failed to merge config source %q: %w", src.Name(), err)
+		}
+		reports = append(reports, SourceReport{Name: src.Name(), Keys: keys})
+		l.layers = append(l.layers, lyr)
+	}
+	l.sourceReports = reports
+	return nil
+}
+
+func loadSource(src Source, timeout time.Duration) (map[string]interface{}, error) {
+	if timeout <= 0 {
+		timeout = defaultSourceTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return src.Load(ctx)
+}
+
+// MapSource is an in-memory source, useful for tests and programmatic overrides.
+type MapSource struct {
+	SourceName string
+	Values     map[string]interface{}
+}
+
+// Name returns the name of the source, "map" if unset.
+func (s *MapSource) Name() string {
+	if s.SourceName == "" {
+		return "map"
+	}
+	return s.SourceName
+}
+
+// Load returns a copy of the configured values.
+func (s *MapSource) Load(context.Context) (map[string]interface{}, error) {
+	return unflattenMap(flattenMap(s.Values)), nil
+}
+
+// VaultSource reads a secret from a Vault-style KV secrets engine over HTTP.
+// Both KV version 1 and version 2 response layouts are supported. A secret that does not
+// exist is an error, so that a mistyped path does not silently load defaults.
+type VaultSource struct {
+	Address string       // Base address, e.g. "https://vault.example.com:8200".
+	Path    string       // Secret path, e.g. "secret/data/myapp" for KV v2.
+	Token   string       // Token sent in the X-Vault-Token header.
+	Client  *http.Client // Optional HTTP client, defaults to http.DefaultClient.
+}
+
+// Name returns the name of the source.
+func (s *VaultSource) Name() string { return "vault:" + s.Path }
+
+// Load fetches the secret and returns its key/value pairs.
+func (s *VaultSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	endpoint := strings.TrimSuffix(s.Address, "/") + "/v1/" + strings.TrimPrefix(s.Path, "/")
+	header := http.Header{}
+	if s.Token != "" {
+		header.Set("X-Vault-Token", s.Token)
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	found, err := getJSON(ctx, s.Client, endpoint, header, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("secret %q not found", s.Path)
+	}
+
+	// KV v2 nests the secret under data.data next to data.metadata.
+	if inner, ok := resp.Data["data"].(map[string]interface{}); ok {
+		if _, ok := resp.Data["metadata"]; ok {
+			return inner, nil
+		}
+	}
+	return resp.Data, nil
+}
+
+// ConsulSource reads every key under a prefix from a Consul-style KV store over HTTP.
+// Nested keys such as "myapp/db/url" are mapped to dotted keys such as "db.url". Only keys
+// below the prefix are read: keys of "myapp2" are not read for the prefix "myapp".
+type ConsulSource struct {
+	Address string       // Base address, e.g. "http://localhost:8500".
+	Prefix  string       // Key prefix, e.g. "myapp".
+	Token   string       // Optional token sent in the X-Consul-Token header.
+	Client  *http.Client // Optional HTTP client, defaults to http.DefaultClient.
+}
+
+// Name returns the name of the source.
+func (s *ConsulSource) Name() string { return "consul:" + s.Prefix }
+
+// Load fetches all keys under the prefix.
+func (s *ConsulSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	prefix := strings.Trim(s.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	endpoint := strings.TrimSuffix(s.Address, "/") + "/v1/kv/" + prefix + "?recurse=true"
+	header := http.Header{}
+	if s.Token != "" {
+		header.Set("X-Consul-Token", s.Token)
+	}
+
+	var pairs []struct {
+		Key   string  `json:"Key"`
+		Value *string `json:"Value"`
+	}
+	found, err := getJSON(ctx, s.Client, endpoint, header, &pairs)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		if !strings.HasPrefix(pair.Key, prefix) {
+			continue
+		}
+		key := strings.Trim(strings.TrimPrefix(pair.Key, prefix), "/")
+		if key == "" || pair.Value == nil || strings.HasSuffix(pair.Key, "/") {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(*pair.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value of %q: %w", pair.Key, err)
+		}
+		values[strings.ReplaceAll(key, "/", ".")] = string(decoded)
+	}
+	return values, nil
+}
+
+// DirSource reads one value per file from a directory, such as a Kubernetes secret
+// or ConfigMap volume. The file name is the key and its trimmed content the value;
+// subdirectories produce dotted keys. Hidden entries (e.g. "..data") are ignored.
+type DirSource struct {
+	Path string
+	Fs   afero.Fs // Optional filesystem, defaults to the OS filesystem.
+}
+
+// Name returns the name of the source.
+func (s *DirSource) Name() string { return "dir:" + s.Path }
+
+// Load reads all files below the directory.
+func (s *DirSource) Load(context.Context) (map[string]interface{}, error) {
+	fs := s.Fs
+	if fs == nil {
+		fs = afero.NewOsFs()
+	}
+	values := make(map[string]interface{})
+	if err := readDirValues(fs, s.Path, "", values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func readDirValues(fs afero.Fs, dir, prefix string, values map[string]interface{}) error {
+	entries, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		key := entry.Name()
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		// Stat follows the symlinks Kubernetes uses for mounted volumes.
+		info, err := fs.Stat(path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if err := readDirValues(fs, path, key, values); err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, err := afero.ReadFile(fs, path)
+		if err != nil {
+			return err
+		}
+		values[key] = strings.TrimSpace(string(content))
+	}
+	return nil
+}
+
+// HTTPSource reads a JSON object from an HTTP endpoint.
+type HTTPSource struct {
+	URL    string
+	Header http.Header  // Optional request headers, e.g. for authorization.
+	Client *http.Client // Optional HTTP client, defaults to http.DefaultClient.
+}
+
+// Name returns the name of the source.
+func (s *HTTPSource) Name() string { return "http:" + s.URL }
+
+// Load fetches and decodes the JSON object.
+func (s *HTTPSource) Load(ctx context.Context) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	if _, err := getJSON(ctx, s.Client, s.URL, s.Header, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// getJSON performs a GET request and decodes the JSON response into out.
+// A 404 response is not an error; it reports found as false.
+func getJSON(ctx context.Context, client *http.Client, endpoint string, header http.Header, out interface{}) (found bool, err error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	for k, vals := range header {
+		for _, v := range vals {
+			req.Header.Add(k, v)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if errors.Is(err, io.EOF) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return true, nil
+}
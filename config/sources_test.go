@@ -0,0 +1,186 @@
+package config_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/raystack/salt/config"
+)
+
+func TestSourcesOverrideFileInRegistrationOrder(t *testing.T) {
+	configFilePath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, configFilePath, "server:\n  port: 8080\n  host: file-host\nlog_level: info\n")
+
+	loader := config.NewLoader(
+		config.WithFile(configFilePath),
+		config.WithSource(&config.MapSource{SourceName: "first", Values: map[string]interface{}{
+			"server.host": "first-host",
+			"log_level":   "debug",
+		}}),
+		config.WithSource(&config.MapSource{SourceName: "second", Values: map[string]interface{}{
+			"server": map[string]interface{}{"host": "second-host"},
+		}}),
+	)
+
+	cfg := &Config{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if cfg.Server.Port != 8080 {
+		t.Errorf("Expected server.port from file, got %d", cfg.Server.Port)
+	}
+	if cfg.Server.Host != "second-host" {
+		t.Errorf("Expected server.host from the last source, got %s", cfg.Server.Host)
+	}
+	if cfg.LogLevel != "debug" {
+		t.Errorf("Expected log_level from the first source, got %s", cfg.LogLevel)
+	}
+
+	want := []config.SourceReport{
+		{Name: "first", Keys: []string{"log_level", "server.host"}},
+		{Name: "second", Keys: []string{"server.host"}},
+	}
+	if got := loader.Sources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected source report: %+v", got)
+	}
+}
+
+func TestEnvOverridesSources(t *testing.T) {
+	setEnv(t, "SERVER_HOST", "env-host")
+	defer unsetEnv(t, "SERVER_HOST")
+
+	loader := config.NewLoader(config.WithSource(&config.MapSource{Values: map[string]interface{}{
+		"server.host": "source-host",
+	}}))
+
+	cfg := &Config{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "env-host" {
+		t.Errorf("Expected environment variable to override source, got %s", cfg.Server.Host)
+	}
+}
+
+func TestVaultSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/secret/data/myapp" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"data":     map[string]interface{}{"server.host": "vault-host"},
+				"metadata": map[string]interface{}{"version": 3},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	src := &config.VaultSource{Address: srv.URL, Path: "secret/data/myapp", Token: "s.token"}
+	cfg := &Config{}
+	if err := config.NewLoader(config.WithSource(src)).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "vault-host" {
+		t.Errorf("Expected server.host from vault, got %s", cfg.Server.Host)
+	}
+
+	src.Token = "wrong"
+	if err := config.NewLoader(config.WithSource(src)).Load(&Config{}); err == nil {
+		t.Error("Expected an error for a rejected vault request")
+	}
+
+	src = &config.VaultSource{Address: srv.URL, Path: "secret/data/my-app", Token: "s.token"}
+	if err := config.NewLoader(config.WithSource(src)).Load(&Config{}); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected a not found error for a mistyped vault path, got %v", err)
+	}
+}
+
+func TestConsulSource(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("recurse") != "true" {
+			http.NotFound(w, r)
+			return
+		}
+		// Consul matches keys by prefix, including those of sibling prefixes
+		var pairs []map[string]interface{}
+		for _, pair := range []map[string]interface{}{
+			{"Key": "myapp/", "Value": nil},
+			{"Key": "myapp/server/port", "Value": encode("7070")},
+			{"Key": "myapp/server/host", "Value": encode("consul-host")},
+			{"Key": "myapp2/server/port", "Value": encode("9090")},
+		} {
+			if strings.HasPrefix(pair["Key"].(string), strings.TrimPrefix(r.URL.Path, "/v1/kv/")) {
+				pairs = append(pairs, pair)
+			}
+		}
+		json.NewEncoder(w).Encode(pairs)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{}
+	loader := config.NewLoader(config.WithSource(&config.ConsulSource{Address: srv.URL, Prefix: "myapp"}))
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 7070 || cfg.Server.Host != "consul-host" {
+		t.Errorf("Unexpected values from consul: %+v", cfg.Server)
+	}
+}
+
+func TestDirSource(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "server"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeConfigFile(t, filepath.Join(dir, "server", "host"), "dir-host\n")
+	writeConfigFile(t, filepath.Join(dir, "log_level"), "warn")
+	writeConfigFile(t, filepath.Join(dir, "..data"), "ignored")
+
+	src := &config.DirSource{Path: dir}
+	loader := config.NewLoader(config.WithSource(src))
+	cfg := &Config{}
+	if err := loader.Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Host != "dir-host" || cfg.LogLevel != "warn" {
+		t.Errorf("Unexpected values from directory: %+v", cfg)
+	}
+	if keys := loader.Sources()[0].Keys; !reflect.DeepEqual(keys, []string{"log_level", "server.host"}) {
+		t.Errorf("Unexpected keys reported for directory source: %v", keys)
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer abc" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"server": {"port": 6060}}`))
+	}))
+	defer srv.Close()
+
+	src := &config.HTTPSource{URL: srv.URL, Header: http.Header{"Authorization": {"Bearer abc"}}}
+	cfg := &Config{}
+	if err := config.NewLoader(config.WithSource(src)).Load(cfg); err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	if cfg.Server.Port != 6060 {
+		t.Errorf("Expected server.port from HTTP source, got %d", cfg.Server.Port)
+	}
+}
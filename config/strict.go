@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// RuleUnknown is the rule reported in strict mode for keys that do not map to a struct field.
+const RuleUnknown = "unknown"
+
+// WithStrict enables strict mode: `Load` fails with a *ValidationError listing every key set in
+// a configuration file or source, and every `<PREFIX>_*` environment variable, that does not
+// map to a field of the configuration struct, with suggestions for likely typos.
+// Environment variables are only checked when an environment prefix is set.
+func WithStrict() Option {
+	return func(l *Loader) {
+		l.strict = true
+	}
+}
+
+// structKeys records the lowercase dotted keys of the fields of the struct type t. Keys of maps
+// and interfaces are recorded as open, since arbitrary keys may be nested below them.
+func structKeys(t reflect.Type, prefix string, keys map[string]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tagParts := strings.Split(field.Tag.Get("mapstructure"), ",")
+		if tagParts[0] == "-" {
+			continue
+		}
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if slices.Contains(tagParts[1:], "squash") && fieldType.Kind() == reflect.Struct {
+			structKeys(fieldType, prefix, keys)
+			continue
+		}
+
+		key := fieldKey(field, prefix)
+		switch {
+		case fieldType.Kind() == reflect.Struct && fieldType != timeType:
+			structKeys(fieldType, key, keys)
+		case fieldType.Kind() == reflect.Map || fieldType.Kind() == reflect.Interface:
+			keys[key] = true
+		default:
+			keys[key] = false
+		}
+	}
+}
+
+// knownKey reports whether the key maps to a field, is nested below an open field, or names a section.
+func knownKey(keys map[string]bool, key string) bool {
+	if _, ok := keys[key]; ok {
+		return true
+	}
+	for known, open := range keys {
+		if (open && strings.HasPrefix(key, known+".")) || strings.HasPrefix(known, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownKeys reports the keys set in the loaded layers or through prefixed environment
+// variables that do not map to a field of the configuration struct type t.
+func (l *Loader) unknownKeys(t reflect.Type) []FieldError {
+	keys := make(map[string]bool)
+	structKeys(t, "", keys)
+	if len(l.migrations) > 0 {
+		keys[versionKey] = false
+	}
+	candidates := make([]string, 0, len(keys))
+	for key := range keys {
+		candidates = append(candidates, key)
+	}
+	sort.Strings(candidates)
+
+	var unknown []FieldError
+	seen := make(map[string]bool)
+	for i := len(l.layers) - 1; i >= 0; i-- {
+		lyr := l.layers[i]
+		layerKeys := make([]string, 0, len(lyr.values))
+		for key := range lyr.values {
+			layerKeys = append(layerKeys, key)
+		}
+		sort.Strings(layerKeys)
+
+		for _, key := range layerKeys {
+			if seen[key] || knownKey(keys, key) {
+				continue
+			}
+			seen[key] = true
+			location := lyr.fileLocation(key)
+			if location == "" {
+				location = lyr.name
+			}
+			value := lyr.values[key]
+			if isSecretKey(key) {
+				value = mask(value)
+			}
+			unknown = append(unknown, FieldError{
+				Key:      key,
+				Location: location,
+				Rule:     RuleUnknown,
+				Value:    value,
+				Message:  unknownMessage(key, candidates),
+			})
+		}
+	}
+
+	if prefix := l.v.GetEnvPrefix(); prefix != "" {
+		unknown = append(unknown, l.unknownEnvVars(strings.ToUpper(prefix)+"_", keys, candidates)...)
+	}
+	return unknown
+}
+
+func (l *Loader) unknownEnvVars(prefix string, keys map[string]bool, candidates []string) []FieldError {
+	known := map[string]bool{l.envVarName(profileKey): true}
+	var open []string
+	for key, isOpen := range keys {
+		known[l.envVarName(key)] = true
+		for _, name := range l.aliasEnvVars(key) {
+			known[name] = true
+		}
+		if isOpen {
+			open = append(open, l.envVarName(key)+"_")
+		}
+	}
+
+	// Env var names cannot tell "." from "_", so suggest keys by their env var names.
+	suggestions := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		suggestions[i] = l.envVarName(candidate)
+	}
+
+	var unknown []FieldError
+	environ := os.Environ()
+	sort.Strings(environ)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, prefix) || known[name] {
+			continue
+		}
+		if slices.ContainsFunc(open, func(p string) bool { return strings.HasPrefix(name, p) }) {
+			continue
+		}
+		key := strings.ToLower(strings.TrimPrefix(name, prefix))
+		if isSecretKey(key) {
+			value = maskedValue
+		}
+		unknown = append(unknown, FieldError{
+			Key:      key,
+			EnvVar:   name,
+			Location: "env " + name,
+			Rule:     RuleUnknown,
+			Value:    value,
+			Message:  unknownMessage(name, suggestions),
+		})
+	}
+	return unknown
+}
+
+// unknownMessage describes an unknown key, suggesting the closest candidate if it is likely a typo.
+func unknownMessage(key string, candidates []string) string {
+	best, bestDistance := "", -1
+	for _, candidate := range candidates {
+		if d := levenshtein(key, candidate); bestDistance < 0 || d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	if bestDistance >= 0 && bestDistance <= max(2, len(key)/3) {
+		return fmt.Sprintf("unknown key, did you mean %q?", best)
+	}
+	return "unknown key"
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,78 @@
+package config_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/raystack/salt/config"
+)
+
+type StrictConfig struct {
+	Server struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	} `mapstructure:"server"`
+	DB struct {
+		MaxOpenConns int `mapstructure:"max_open_conns"`
+	} `mapstructure:"db"`
+	Labels map[string]string `mapstructure:"labels"`
+}
+
+func TestStrictReportsUnknownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "server:\n  prot: 8080\ndb:\n  max_open_con: 10\nlabels:\n  team: core\nfrobnicate: true\n")
+
+	setEnv(t, "MYAPP_SERVER_HOTS", "localhost")
+	defer unsetEnv(t, "MYAPP_SERVER_HOTS")
+	setEnv(t, "MYAPP_LABELS_ENV", "prod")
+	defer unsetEnv(t, "MYAPP_LABELS_ENV")
+
+	err := config.NewLoader(
+		config.WithFile(path),
+		config.WithEnvPrefix("MYAPP"),
+		config.WithStrict(),
+	).Load(&StrictConfig{})
+
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a ValidationError, got %T: %v", err, err)
+	}
+
+	want := []config.FieldError{
+		{Key: "db.max_open_con", Location: path + ":4", Rule: config.RuleUnknown, Value: 10,
+			Message: `unknown key, did you mean "db.max_open_conns"?`},
+		{Key: "frobnicate", Location: path + ":7", Rule: config.RuleUnknown, Value: true,
+			Message: "unknown key"},
+		{Key: "server.prot", Location: path + ":2", Rule: config.RuleUnknown, Value: 8080,
+			Message: `unknown key, did you mean "server.port"?`},
+		{Key: "server_hots", EnvVar: "MYAPP_SERVER_HOTS", Location: "env MYAPP_SERVER_HOTS", Rule: config.RuleUnknown,
+			Value: "localhost", Message: `unknown key, did you mean "MYAPP_SERVER_HOST"?`},
+	}
+	if !reflect.DeepEqual(verr.Fields, want) {
+		t.Errorf("Unexpected field errors:\n got: %+v\nwant: %+v", verr.Fields, want)
+	}
+}
+
+func TestStrictAcceptsKnownKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "server:\n  port: 8080\ndb:\n  max_open_conns: 10\nlabels:\n  team: core\n")
+
+	cfg := &StrictConfig{}
+	if err := config.NewLoader(config.WithFile(path), config.WithStrict()).Load(cfg); err != nil {
+		t.Fatalf("Expected known keys to load in strict mode, got: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.DB.MaxOpenConns != 10 || cfg.Labels["team"] != "core" {
+		t.Errorf("Unexpected configuration: %+v", cfg)
+	}
+}
+
+func TestUnknownKeysIgnoredWithoutStrict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "server:\n  prot: 8080\n")
+
+	if err := config.NewLoader(config.WithFile(path)).Load(&StrictConfig{}); err != nil {
+		t.Fatalf("Expected unknown keys to be ignored, got: %v", err)
+	}
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mcuadros/go-defaults"
+	"gopkg.in/yaml.v3"
+)
+
+// InitOption customizes the file written by `Init`.
+type InitOption func(*initOptions)
+
+type initOptions struct {
+	optionalSections bool
+	merge            bool
+}
+
+// WithOptionalSections includes optional sections, i.e. fields holding a pointer to a struct,
+// in the generated YAML template. They are commented out so that users can enable them by
+// uncommenting.
+func WithOptionalSections() InitOption {
+	return func(o *initOptions) {
+		o.optionalSections = true
+	}
+}
+
+// WithMerge lets `Init` regenerate an existing configuration file non-destructively: keys
+// missing from the file are added with their defaults (and comments, for YAML), while
+// existing values are kept. Comments are kept in YAML and dotenv files.
+func WithMerge() InitOption {
+	return func(o *initOptions) {
+		o.merge = true
+	}
+}
+
+// scaffold renders the defaulted config in the given format, merged into existing when it is not nil.
+func (l *Loader) scaffold(format string, config interface{}, opts initOptions, existing []byte) ([]byte, error) {
+	// New files are written in the latest version; existing files keep theirs for `Migrate`.
+	version := 0
+	if existing == nil && len(l.migrations) > 0 {
+		version = l.latestVersion()
+	}
+
+	if format == formatYAML {
+		template, err := l.yamlTemplate(config, opts.optionalSections)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return mergeYAMLTemplate(existing, template)
+		}
+		if version > 0 {
+			header := fmt.Sprintf("# Version of this file, upgraded by migrations\n%s: %d\n\n", versionKey, version)
+			template = append([]byte(header), template...)
+		}
+		return template, nil
+	}
+
+	values, err := flattenStruct(config)
+	if err != nil {
+		return nil, err
+	}
+	for key := range secretKeys(reflect.TypeOf(config)) {
+		values[key] = l.secretPlaceholder(key)
+	}
+	settings := unflattenMap(values)
+	if version > 0 {
+		settings[versionKey] = version
+	}
+
+	switch {
+	case existing == nil:
+		return l.encodeConfig(format, settings, nil)
+	case format == formatDotenv:
+		return mergeDotenv(existing, l.encodeDotenv(encodableSettings(settings)))
+	default:
+		current, _, err := l.decodeConfig(format, existing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse existing configuration: %w", err)
+		}
+		merged := flattenMap(settings)
+		for key, value := range flattenMap(current) {
+			merged[key] = value
+		}
+		return l.encodeConfig(format, unflattenMap(merged), nil)
+	}
+}
+
+// yamlTemplate renders the defaulted config as YAML with every key documented from its struct
+// tags: the `desc` description, allowed `oneof` values, environment variable, flag and default.
+func (l *Loader) yamlTemplate(config interface{}, optionalSections bool) ([]byte, error) {
+	w := &templateWriter{
+		l:                l,
+		optionalSections: optionalSections,
+		secretKeys:       secretKeys(reflect.TypeOf(config)),
+	}
+	if err := w.writeStruct(reflect.ValueOf(config).Elem(), "", "", true, 0, -1); err != nil {
+		return nil, err
+	}
+	return w.buf.Bytes(), nil
+}
+
+type templateWriter struct {
+	l                *Loader
+	optionalSections bool
+	secretKeys       map[string]bool
+	buf              bytes.Buffer
+}
+
+// writeStruct writes the fields of the struct v. prefix is the dotted key of v, flagPrefix the
+// flag name of v and bindable whether its fields can be bound to flags (see bindFlags).
+// Lines are commented out from the column commentAt, or not at all if it is negative.
+func (w *templateWriter) writeStruct(v reflect.Value, prefix, flagPrefix string, bindable bool, indent, commentAt int) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tagParts := strings.Split(field.Tag.Get("mapstructure"), ",")
+		if tagParts[0] == "-" {
+			continue
+		}
+		if slices.Contains(tagParts[1:], "squash") {
+			if err := w.writeStruct(v.Field(i), prefix, flagPrefix, bindable, indent, commentAt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		key := fieldKey(field, prefix)
+		name := key[strings.LastIndex(key, ".")+1:]
+		flag, fieldBindable := field.Tag.Get("cmdx"), bindable
+		if flag == "" {
+			fieldBindable = false
+		} else if flagPrefix != "" {
+			flag = flagPrefix + "." + flag
+		}
+
+		if indent == 0 && w.buf.Len() > 0 {
+			w.buf.WriteString("\n")
+		}
+
+		value := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct && field.Type != timeType:
+			w.writeComments(indent, commentAt, field.Tag.Get("desc"))
+			w.writeLine(indent, commentAt, name+":")
+			if err := w.writeStruct(value, key, flag, fieldBindable, indent+2, commentAt); err != nil {
+				return err
+			}
+
+		case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct && field.Type.Elem() != timeType:
+			if value.IsNil() && !w.optionalSections {
+				continue
+			}
+			sectionAt := commentAt
+			if value.IsNil() {
+				// Optional sections are written commented out, filled with their defaults.
+				value = reflect.New(field.Type.Elem())
+				defaults.SetDefaults(value.Interface())
+				w.writeComments(indent, commentAt, field.Tag.Get("desc"), "optional section, uncomment to enable")
+				if sectionAt < 0 {
+					sectionAt = indent
+				}
+			} else {
+				w.writeComments(indent, commentAt, field.Tag.Get("desc"))
+			}
+			w.writeLine(indent, sectionAt, name+":")
+			if err := w.writeStruct(value.Elem(), key, flag, fieldBindable, indent+2, sectionAt); err != nil {
+				return err
+			}
+
+		default:
+			if err := w.writeField(field, value, key, name, flag, fieldBindable, indent, commentAt); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (w *templateWriter) writeField(field reflect.StructField, value reflect.Value, key, name, flag string, bindable bool, indent, commentAt int) error {
+	var comments []string
+	if desc := field.Tag.Get("desc"); desc != "" {
+		comments = append(comments, desc)
+	}
+	required := false
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		tag, param, _ := strings.Cut(rule, "=")
+		switch tag {
+		case "required":
+			required = true
+		case "oneof":
+			comments = append(comments, "allowed values: "+strings.Join(strings.Fields(param), ", "))
+		}
+	}
+
+	details := []string{"env: " + w.l.envVarName(key)}
+	if bindable {
+		details = append(details, "flag: --"+flag)
+	}
+	if def, ok := field.Tag.Lookup("default"); ok {
+		details = append(details, "default: "+def)
+	}
+	if required {
+		details = append(details, "required")
+	}
+	comments = append(comments, strings.Join(details, " | "))
+	w.writeComments(indent, commentAt, comments...)
+
+	var v interface{}
+	switch {
+	case w.secretKeys[key]:
+		v = w.l.secretPlaceholder(key)
+	case field.Type == durationType:
+		v = time.Duration(value.Int()).String()
+	case field.Type.Kind() == reflect.String:
+		v = value.String()
+	default:
+		v = value.Interface()
+	}
+
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) == 1 && !strings.HasPrefix(lines[0], "- ") {
+		w.writeLine(indent, commentAt, name+": "+lines[0])
+		return nil
+	}
+	w.writeLine(indent, commentAt, name+":")
+	for _, line := range lines {
+		w.writeLine(indent+2, commentAt, line)
+	}
+	return nil
+}
+
+func (w *templateWriter) writeComments(indent, commentAt int, comments ...string) {
+	for _, comment := range comments {
+		for _, line := range strings.Split(comment, "\n") {
+			if line != "" {
+				w.writeLine(indent, commentAt, "# "+line)
+			}
+		}
+	}
+}
+
+// writeLine writes an indented line. Commented lines carry "# " at the column commentAt, so
+// that removing it restores valid YAML.
+func (w *templateWriter) writeLine(indent, commentAt int, line string) {
+	if commentAt >= 0 {
+		w.buf.WriteString(strings.Repeat(" ", commentAt) + "# " + strings.Repeat(" ", indent-commentAt) + line + "\n")
+		return
+	}
+	w.buf.WriteString(strings.Repeat(" ", indent) + line + "\n")
+}
+
+// mergeYAMLTemplate adds the keys of template missing from existing, with their comments,
+// leaving existing values and comments untouched.
+func mergeYAMLTemplate(existing, template []byte) ([]byte, error) {
+	var current, generated yaml.Node
+	if err := yaml.Unmarshal(existing, &current); err != nil {
+		return nil, fmt.Errorf("failed to parse existing configuration: %w", err)
+	}
+	if err := yaml.Unmarshal(template, &generated); err != nil {
+		return nil, err
+	}
+	if len(current.Content) == 0 || current.Content[0].Kind != yaml.MappingNode {
+		return template, nil
+	}
+	if len(generated.Content) > 0 {
+		mergeMissingKeys(current.Content[0], generated.Content[0])
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&current); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mergeMissingKeys(dst, src *yaml.Node) {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return
+	}
+	existing := make(map[string]*yaml.Node, len(dst.Content)/2)
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		existing[strings.ToLower(dst.Content[i].Value)] = dst.Content[i+1]
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		if value, ok := existing[strings.ToLower(src.Content[i].Value)]; ok {
+			mergeMissingKeys(value, src.Content[i+1])
+			continue
+		}
+		dst.Content = append(dst.Content, src.Content[i], src.Content[i+1])
+	}
+}
+
+// mergeDotenv appends the assignments of generated whose variables are not set in existing.
+func mergeDotenv(existing, generated []byte) ([]byte, error) {
+	set := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "export ")
+		if name, _, ok := strings.Cut(line, "="); ok && !strings.HasPrefix(line, "#") {
+			set[strings.TrimSpace(name)] = true
+		}
+	}
+
+	out := bytes.NewBuffer(existing)
+	if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+		out.WriteString("\n")
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(generated), "\n"), "\n") {
+		if name, _, ok := strings.Cut(line, "="); ok && !set[name] {
+			out.WriteString(line + "\n")
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// secretPlaceholder returns the placeholder scaffolded for a secret: a reference to its
+// environment variable that resolves to an empty value when the variable is unset, so that
+// scaffolded files load without every secret being set.
+func (l *Loader) secretPlaceholder(key string) string {
+	return "${" + l.envVarName(key) + ":-}"
+}
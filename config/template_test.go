@@ -0,0 +1,126 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/raystack/salt/config"
+)
+
+type TemplateConfig struct {
+	Server struct {
+		Port    int           `mapstructure:"port" cmdx:"port" default:"8080" desc:"Port to listen on" validate:"required,min=1"`
+		Timeout time.Duration `mapstructure:"timeout" default:"5s"`
+		TLS     *struct {
+			Cert string `mapstructure:"cert" desc:"Path to the certificate"`
+		} `mapstructure:"tls" desc:"TLS settings"`
+	} `mapstructure:"server" cmdx:"server" desc:"HTTP server"`
+	LogLevel string `mapstructure:"log_level" cmdx:"log_level" default:"info" validate:"oneof=debug info warn error"`
+	Password string `mapstructure:"password" secret:"true"`
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestInitWritesCommentedTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	loader := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP"))
+	if err := loader.Init(&TemplateConfig{}, config.WithOptionalSections()); err != nil {
+		t.Fatalf("Failed to initialize configuration: %v", err)
+	}
+
+	content := readFile(t, path)
+	for _, want := range []string{
+		"# HTTP server\nserver:\n",
+		"  # Port to listen on\n  # env: MYAPP_SERVER_PORT | flag: --server.port | default: 8080 | required\n  port: 8080\n",
+		"  timeout: 5s\n",
+		"  # TLS settings\n  # optional section, uncomment to enable\n  # tls:\n  #   # Path to the certificate\n",
+		"# allowed values: debug, info, warn, error\n# env: MYAPP_LOG_LEVEL | flag: --log_level | default: info\nlog_level: info\n",
+		"password: ${MYAPP_PASSWORD:-}\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected template to contain %q, got:\n%s", want, content)
+		}
+	}
+
+	setEnv(t, "MYAPP_PASSWORD", "hunter2")
+	defer unsetEnv(t, "MYAPP_PASSWORD")
+
+	cfg := &TemplateConfig{}
+	if err := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP")).Load(cfg); err != nil {
+		t.Fatalf("Failed to load generated template: %v", err)
+	}
+	if cfg.Server.Port != 8080 || cfg.Server.TLS != nil || cfg.Password != "hunter2" {
+		t.Errorf("Unexpected configuration from template: %+v", cfg)
+	}
+}
+
+func TestInitOutputLoadsWithoutSecrets(t *testing.T) {
+	for _, name := range []string{"config.yaml", "config.json", "config.toml", "app.env"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			loader := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP"))
+			if err := loader.Init(&TemplateConfig{}); err != nil {
+				t.Fatalf("Failed to initialize configuration: %v", err)
+			}
+
+			cfg := &TemplateConfig{}
+			if err := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP")).Load(cfg); err != nil {
+				t.Fatalf("Failed to load initialized configuration without secrets set: %v", err)
+			}
+			if cfg.Password != "" || cfg.Server.Port != 8080 {
+				t.Errorf("Unexpected configuration: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestInitExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, path, "# my settings\nserver:\n  port: 9090 # production port\n")
+
+	loader := config.NewLoader(config.WithFile(path))
+	if err := loader.Init(&TemplateConfig{}); err == nil {
+		t.Error("Expected an error when the configuration file exists")
+	}
+
+	if err := loader.Init(&TemplateConfig{}, config.WithMerge()); err != nil {
+		t.Fatalf("Failed to merge configuration: %v", err)
+	}
+	content := readFile(t, path)
+	for _, want := range []string{
+		"# my settings\nserver:\n  port: 9090 # production port\n",
+		"  # env: SERVER_TIMEOUT | default: 5s\n  timeout: 5s\n",
+		"log_level: info\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected merged file to contain %q, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestInitMergeDotenv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.env")
+	writeConfigFile(t, path, "# overrides\nMYAPP_SERVER_PORT=9090\n")
+
+	loader := config.NewLoader(config.WithFile(path), config.WithEnvPrefix("MYAPP"))
+	if err := loader.Init(&TemplateConfig{}, config.WithMerge()); err != nil {
+		t.Fatalf("Failed to merge configuration: %v", err)
+	}
+	content := readFile(t, path)
+	if !strings.HasPrefix(content, "# overrides\nMYAPP_SERVER_PORT=9090\n") || strings.Count(content, "MYAPP_SERVER_PORT=") != 1 {
+		t.Errorf("Expected existing assignments to be kept, got:\n%s", content)
+	}
+	if !strings.Contains(content, "MYAPP_LOG_LEVEL=info\n") || !strings.Contains(content, "MYAPP_SERVER_TIMEOUT=5s\n") {
+		t.Errorf("Expected missing assignments to be added, got:\n%s", content)
+	}
+}
@@ -0,0 +1,317 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/afero"
+)
+
+const defaultWatchDebounce = 100 * time.Millisecond
+
+// Change describes a configuration reload that passed validation.
+type Change[T any] struct {
+	Old     *T       // Configuration before the reload.
+	New     *T       // Configuration after the reload.
+	Changed []string // Sorted dotted keys whose values differ between Old and New.
+}
+
+// Watcher keeps a configuration struct in sync with its configuration files,
+// including overlays, profile variants and included files.
+//
+// Every reload re-reads the file and environment variables, re-applies defaults
+// and runs validation. The current configuration is only replaced when the reloaded
+// one is valid; otherwise the error is reported to the error callbacks and the
+// previous configuration stays in effect.
+type Watcher[T any] struct {
+	loader   *Loader
+	debounce time.Duration
+
+	// reloadMu serializes reloads, so that they are applied in the order they happen.
+	reloadMu sync.Mutex
+
+	mu      sync.RWMutex
+	current *T
+	subs    []func(Change[T])
+	errSubs []func(error)
+
+	fsw       *fsnotify.Watcher
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+// WatchOption defines a functional option for configuring a Watcher.
+type WatchOption func(o *watchOptions)
+
+type watchOptions struct {
+	debounce time.Duration
+}
+
+// WithWatchDebounce sets how long the watcher waits for file events to settle
+// before reloading. Editors commonly emit several events for a single save.
+func WithWatchDebounce(d time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		if d > 0 {
+			o.debounce = d
+		}
+	}
+}
+
+// Watch loads the configuration into a new T and starts watching the loader's
+// configuration file for changes. Watching stops when ctx is done or Close is called.
+//
+// Example:
+//
+//	w, err := config.Watch[Config](ctx, loader)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	w.Subscribe(func(c config.Change[Config]) {
+//	    log.Printf("configuration changed: %v", c.Changed)
+//	})
+//	w.OnError(func(err error) {
+//	    log.Printf("rejected configuration change: %v", err)
+//	})
+func Watch[T any](ctx context.Context, l *Loader, opts ...WatchOption) (*Watcher[T], error) {
+	o := watchOptions{debounce: defaultWatchDebounce}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	l.mu.RLock()
+	path := l.v.ConfigFileUsed()
+	_, osFs := l.fs.(*afero.OsFs)
+	l.mu.RUnlock()
+	if path == "" {
+		return nil, errors.New("no configuration file specified for watching")
+	}
+	if !osFs {
+		return nil, errors.New("watching requires the OS filesystem")
+	}
+
+	current := new(T)
+	if err := l.Load(current); err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	// Watch directories rather than files so that atomic renames and symlink
+	// swaps (as done by editors and Kubernetes volumes) are observed.
+	files := l.watchedFiles()
+	dirs := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+		dirs[dir] = true
+		// Missing overlays and profile variants are skipped, along with their directories
+		if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err := fsw.Add(dir); err != nil {
+			fsw.Close()
+			return nil, fmt.Errorf("failed to watch configuration directory: %w", err)
+		}
+	}
+
+	w := &Watcher[T]{
+		loader:   l,
+		debounce: o.debounce,
+		current:  current,
+		fsw:      fsw,
+		done:     make(chan struct{}),
+	}
+	go w.run(ctx, files)
+	return w, nil
+}
+
+// Current returns the most recent valid configuration.
+// The returned value must be treated as read-only.
+func (w *Watcher[T]) Current() *T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe registers a callback invoked after every reload that changed at least one key.
+func (w *Watcher[T]) Subscribe(fn func(Change[T])) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subs = append(w.subs, fn)
+}
+
+// OnError registers a callback invoked when a reload is rejected.
+func (w *Watcher[T]) OnError(fn func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errSubs = append(w.errSubs, fn)
+}
+
+// Reload re-reads the configuration immediately, e.g. in response to SIGHUP.
+// An invalid configuration is rejected, reported to the error callbacks and returned;
+// the loader then keeps the values of the previous configuration.
+func (w *Watcher[T]) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	next := new(T)
+	if err := w.loader.load(next, true); err != nil {
+		w.notifyError(err)
+		return err
+	}
+
+	w.mu.Lock()
+	prev := w.current
+	changed, err := diffKeys(prev, next)
+	if err != nil {
+		w.mu.Unlock()
+		w.notifyError(err)
+		return err
+	}
+	w.current = next
+	subs := append([]func(Change[T]){}, w.subs...)
+	w.mu.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+	change := Change[T]{Old: prev, New: next, Changed: changed}
+	for _, fn := range subs {
+		fn(change)
+	}
+	return nil
+}
+
+// Close stops watching the configuration file.
+func (w *Watcher[T]) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		err = w.fsw.Close()
+	})
+	return err
+}
+
+func (w *Watcher[T]) run(ctx context.Context, files []string) {
+	defer w.Close()
+
+	realPaths := make(map[string]string, len(files))
+	for _, file := range files {
+		realPaths[file], _ = filepath.EvalSymlinks(file)
+	}
+
+	var timer *time.Timer
+	var pending <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			changed := false
+			for file, realPath := range realPaths {
+				currentPath, _ := filepath.EvalSymlinks(file)
+				touched := filepath.Clean(event.Name) == file && event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Remove|fsnotify.Rename)
+				swapped := currentPath != realPath
+				if touched || swapped {
+					realPaths[file] = currentPath
+					changed = true
+				}
+			}
+			if !changed {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(w.debounce)
+			} else {
+				timer.Reset(w.debounce)
+			}
+			pending = timer.C
+		case <-pending:
+			pending = nil
+			_ = w.Reload()
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			w.notifyError(fmt.Errorf("configuration watcher: %w", err))
+		}
+	}
+}
+
+func (w *Watcher[T]) notifyError(err error) {
+	w.mu.RLock()
+	errSubs := append([]func(error){}, w.errSubs...)
+	w.mu.RUnlock()
+	for _, fn := range errSubs {
+		fn(err)
+	}
+}
+
+// watchedFiles returns the cleaned paths of all configuration files that may affect the next load.
+func (l *Loader) watchedFiles() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	for _, path := range l.configFiles() {
+		add(path)
+	}
+	for _, lyr := range l.layers {
+		if lyr.path != "" {
+			add(lyr.path)
+		}
+	}
+	return files
+}
+
+// diffKeys returns the sorted dotted keys whose values differ between two configuration structs.
+func diffKeys(old, new interface{}) ([]string, error) {
+	oldMap, err := flattenStruct(old)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten configuration: %w", err)
+	}
+	newMap, err := flattenStruct(new)
+	if err != nil {
+		return nil, fmt.Errorf("failed to flatten configuration: %w", err)
+	}
+
+	var changed []string
+	for key, value := range newMap {
+		if prev, ok := oldMap[key]; !ok || !reflect.DeepEqual(prev, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldMap {
+		if _, ok := newMap[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
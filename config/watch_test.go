@@ -0,0 +1,116 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/raystack/salt/config"
+)
+
+func writeConfigFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+}
+
+func TestWatchNotifiesSubscribersOnChange(t *testing.T) {
+	configFilePath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, configFilePath, "server:\n  port: 8080\n  host: example.com\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loader := config.NewLoader(config.WithFile(configFilePath))
+	w, err := config.Watch[Config](ctx, loader, config.WithWatchDebounce(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to start watching: %v", err)
+	}
+	defer w.Close()
+
+	if w.Current().Server.Port != 8080 {
+		t.Fatalf("Expected initial port 8080, got %d", w.Current().Server.Port)
+	}
+
+	changes := make(chan config.Change[Config], 1)
+	w.Subscribe(func(c config.Change[Config]) { changes <- c })
+
+	writeConfigFile(t, configFilePath, "server:\n  port: 9090\n  host: example.com\n")
+
+	select {
+	case c := <-changes:
+		if c.Old.Server.Port != 8080 || c.New.Server.Port != 9090 {
+			t.Errorf("Unexpected change values: old=%d new=%d", c.Old.Server.Port, c.New.Server.Port)
+		}
+		if !reflect.DeepEqual(c.Changed, []string{"server.port"}) {
+			t.Errorf("Expected changed keys [server.port], got %v", c.Changed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for configuration change")
+	}
+
+	if w.Current().Server.Port != 9090 {
+		t.Errorf("Expected current port 9090, got %d", w.Current().Server.Port)
+	}
+}
+
+func TestWatchRejectsInvalidChange(t *testing.T) {
+	configFilePath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfigFile(t, configFilePath, "server:\n  port: 8080\n")
+
+	// The file event is debounced past the end of the test, so that only the explicit
+	// Reload below runs the callbacks
+	loader := config.NewLoader(config.WithFile(configFilePath))
+	w, err := config.Watch[Config](context.Background(), loader, config.WithWatchDebounce(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to start watching: %v", err)
+	}
+	defer w.Close()
+
+	var notified, rejected atomic.Bool
+	w.Subscribe(func(config.Change[Config]) { notified.Store(true) })
+	w.OnError(func(error) { rejected.Store(true) })
+
+	writeConfigFile(t, configFilePath, "server:\n  port: -1\n")
+	if err := w.Reload(); err == nil {
+		t.Fatal("Expected validation error for invalid configuration")
+	}
+
+	if !rejected.Load() || notified.Load() {
+		t.Errorf("Expected only the error callback to fire, got rejected=%v notified=%v", rejected.Load(), notified.Load())
+	}
+	if w.Current().Server.Port != 8080 {
+		t.Errorf("Expected previous configuration to stay in effect, got port %d", w.Current().Server.Port)
+	}
+	if port := loader.Get("server.port"); port != 8080 {
+		t.Errorf("Expected the loader to keep the previous values, got port %v", port)
+	}
+}
+
+func TestWatchSkipsMissingOverlayDirectory(t *testing.T) {
+	dir := t.TempDir()
+	configFilePath := filepath.Join(dir, "config.yaml")
+	writeConfigFile(t, configFilePath, "server:\n  port: 8080\n")
+
+	loader := config.NewLoader(config.WithFiles(configFilePath, filepath.Join(dir, "missing", "config.local.yaml")))
+	w, err := config.Watch[Config](context.Background(), loader)
+	if err != nil {
+		t.Fatalf("Failed to start watching: %v", err)
+	}
+	defer w.Close()
+
+	if w.Current().Server.Port != 8080 {
+		t.Errorf("Expected port 8080, got %d", w.Current().Server.Port)
+	}
+}
+
+func TestWatchRequiresConfigFile(t *testing.T) {
+	if _, err := config.Watch[Config](context.Background(), config.NewLoader()); err == nil {
+		t.Fatal("Expected an error when no configuration file is set")
+	}
+}
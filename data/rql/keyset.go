@@ -0,0 +1,171 @@
+package rql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a cursor is malformed or was not created for the sort
+// keys of the query it is used with.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is a position in the results of a query sorted by its Sort keys, used for keyset
+// pagination: the values of the sort keys of the row pages continue from.
+type Cursor struct {
+	// Values of the sort keys, in the order of Query.Sort.
+	Values []any
+	// Backward selects the rows before the position instead of after it.
+	Backward bool
+}
+
+type cursorPayload struct {
+	Sort     string `json:"s"`
+	Values   []any  `json:"v"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// EncodeCursor returns the opaque cursor of the position of item, a struct tagged like the
+// checkStruct of the query, in the results of the query. The sort keys must uniquely identify
+// rows, e.g. by ending with the primary key, and must not be null.
+func EncodeCursor(q *Query, item interface{}, backward bool) (string, error) {
+	val := reflect.Indirect(reflect.ValueOf(item))
+	if val.Kind() != reflect.Struct {
+		return "", fmt.Errorf("cursor item must be a struct, got %T", item)
+	}
+	values := make([]any, len(q.Sort))
+	for i, sort := range q.Sort {
+		idx := searchKeyInsideStruct(sort.Name, val)
+		if idx < 0 {
+			return "", fmt.Errorf("'%s' is not a valid sort key", sort.Name)
+		}
+		values[i] = val.Field(idx).Interface()
+	}
+	return (&Cursor{Values: values, Backward: backward}).Encode(q)
+}
+
+// Encode returns the opaque cursor of the position for the sort keys of the query, e.g. to
+// turn around at the position of a decoded cursor.
+func (c *Cursor) Encode(q *Query) (string, error) {
+	if len(c.Values) != len(q.Sort) {
+		return "", fmt.Errorf("expected %d cursor values, got %d", len(q.Sort), len(c.Values))
+	}
+	payload, err := json.Marshal(cursorPayload{Sort: sortSignature(q.Sort), Values: c.Values, Backward: c.Backward})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// DecodeCursor decodes a cursor created by EncodeCursor for the same sort keys, converting its
+// values to the data types of the keys in checkStruct.
+func DecodeCursor(cursor string, q *Query, checkStruct interface{}) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var payload cursorPayload
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if payload.Sort != sortSignature(q.Sort) || len(payload.Values) != len(q.Sort) {
+		return nil, fmt.Errorf("%w: the sort keys of the query have changed", ErrInvalidCursor)
+	}
+
+	for i, sort := range q.Sort {
+		dataType, err := GetDataTypeOfField(sort.Name, checkStruct)
+		if err != nil {
+			return nil, err
+		}
+		value, ok := cursorValue(payload.Values[i], dataType)
+		if !ok {
+			return nil, fmt.Errorf("%w: value of '%s' is not of type %s", ErrInvalidCursor, sort.Name, dataType)
+		}
+		payload.Values[i] = value
+	}
+	return &Cursor{Values: payload.Values, Backward: payload.Backward}, nil
+}
+
+// cursorValue converts a value decoded from JSON to the data type of its key.
+func cursorValue(value any, dataType string) (any, bool) {
+	switch dataType {
+	case DATATYPE_NUMBER:
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, false
+		}
+		if i, err := number.Int64(); err == nil {
+			return i, true
+		}
+		f, err := number.Float64()
+		return f, err == nil
+	case DATATYPE_DATETIME:
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		return t, err == nil
+	case DATATYPE_BOOL:
+		b, ok := value.(bool)
+		return b, ok
+	default:
+		s, ok := value.(string)
+		return s, ok
+	}
+}
+
+// sortSignature identifies the sort keys a cursor was created for.
+func sortSignature(sort []Sort) string {
+	keys := make([]string, len(sort))
+	for i, s := range sort {
+		keys[i] = strings.ToLower(s.Name) + ":" + s.Order
+	}
+	return strings.Join(keys, ",")
+}
+
+// WithCursor makes BuildSQL select the rows after the cursor position, or before it for a
+// backward cursor, by adding a seek predicate on the sort keys to the WHERE clause. For a
+// backward cursor the sort order is reversed, so that LIMIT selects the rows closest to the
+// position; callers reverse the rows back. Offsets cannot be combined with a cursor.
+func WithCursor(cursor *Cursor) BuildOption {
+	return func(b *builder) {
+		b.cursor = cursor
+	}
+}
+
+// seek returns the predicate selecting rows after the cursor in the sort order, e.g.
+// ("title" > $1 OR ("title" = $2 AND "id" > $3)) for an ascending sort by title then id.
+func (b *builder) seek(sort []Sort, val reflect.Value) (string, error) {
+	if len(b.cursor.Values) != len(sort) {
+		return "", fmt.Errorf("%w: expected %d values, got %d", ErrInvalidCursor, len(sort), len(b.cursor.Values))
+	}
+	var alternatives []string
+	for i := range sort {
+		var conditions []string
+		for j := 0; j <= i; j++ {
+			column := b.quote(fieldColumn(val.Type().Field(searchKeyInsideStruct(sort[j].Name, val))))
+			operator := "="
+			if j == i {
+				operator = ">"
+				if (sort[j].Order == SORT_ORDER_DESC) != b.cursor.Backward {
+					operator = "<"
+				}
+			}
+			conditions = append(conditions, column+" "+operator+" "+b.bind(b.cursor.Values[j]))
+		}
+		condition := strings.Join(conditions, " AND ")
+		if len(conditions) > 1 {
+			condition = "(" + condition + ")"
+		}
+		alternatives = append(alternatives, condition)
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
@@ -0,0 +1,128 @@
+package rql
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	q := &Query{Sort: []Sort{{Name: "created_at", Order: "desc"}, {Name: "title", Order: "asc"}, {Name: "id", Order: "asc"}}}
+	item := Organization{ID: 42, Title: "nasa", CreatedAt: time.Date(2025, 2, 5, 11, 25, 37, 123, time.UTC)}
+
+	cursor, err := EncodeCursor(q, &item, true)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+	got, err := DecodeCursor(cursor, q, Organization{})
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	want := &Cursor{Values: []any{item.CreatedAt, "nasa", int64(42)}, Backward: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeCursor() = %v, want %v", got, want)
+	}
+}
+
+func TestCursorEncodeTurnsAround(t *testing.T) {
+	q := &Query{Sort: []Sort{{Name: "title", Order: "asc"}, {Name: "id", Order: "asc"}}}
+	cursor, err := EncodeCursor(q, Organization{ID: 7, Title: "nasa"}, true)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+	decoded, err := DecodeCursor(cursor, q, Organization{})
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+
+	forward, err := (&Cursor{Values: decoded.Values}).Encode(q)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	got, err := DecodeCursor(forward, q, Organization{})
+	if err != nil {
+		t.Fatalf("DecodeCursor() error = %v", err)
+	}
+	want := &Cursor{Values: []any{"nasa", int64(7)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeCursor() = %v, want %v", got, want)
+	}
+
+	if _, err := (&Cursor{Values: []any{"nasa"}}).Encode(q); err == nil {
+		t.Error("Encode() expected an error for missing values")
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	q := &Query{Sort: []Sort{{Name: "title", Order: "asc"}}}
+	cursor, err := EncodeCursor(q, Organization{Title: "nasa"}, false)
+	if err != nil {
+		t.Fatalf("EncodeCursor() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+		query  *Query
+	}{
+		{name: "Malformed", cursor: "not a cursor!", query: q},
+		{name: "Not JSON", cursor: "bm90IGpzb24", query: q},
+		{name: "Different sort", cursor: cursor, query: &Query{Sort: []Sort{{Name: "title", Order: "desc"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor, tt.query, Organization{}); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestBuildSQLWithCursor(t *testing.T) {
+	q := Query{
+		Filters: []Filter{{Name: "enabled", Operator: "eq", Value: true}},
+		Sort:    []Sort{{Name: "title", Order: "desc"}, {Name: "id", Order: "asc"}},
+		Limit:   11,
+	}
+
+	tests := []struct {
+		name     string
+		cursor   *Cursor
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:   "Forward",
+			cursor: &Cursor{Values: []any{"nasa", int64(42)}},
+			wantSQL: `WHERE "enabled" = $1 AND ("title" < $2 OR ("title" = $3 AND "id" > $4)) ` +
+				`ORDER BY "title" DESC, "id" ASC LIMIT 11`,
+			wantArgs: []any{true, "nasa", "nasa", int64(42)},
+		},
+		{
+			name:   "Backward",
+			cursor: &Cursor{Values: []any{"nasa", int64(42)}, Backward: true},
+			wantSQL: `WHERE "enabled" = $1 AND ("title" > $2 OR ("title" = $3 AND "id" < $4)) ` +
+				`ORDER BY "title" ASC, "id" DESC LIMIT 11`,
+			wantArgs: []any{true, "nasa", "nasa", int64(42)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildSQL(&q, Organization{}, WithCursor(tt.cursor))
+			if err != nil {
+				t.Fatalf("BuildSQL() error = %v", err)
+			}
+			if got.String() != tt.wantSQL {
+				t.Errorf("BuildSQL() sql = %s, want %s", got.String(), tt.wantSQL)
+			}
+			if !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("BuildSQL() args = %v, want %v", got.Args, tt.wantArgs)
+			}
+		})
+	}
+
+	if _, err := BuildSQL(&Query{Sort: q.Sort, Offset: 10}, Organization{}, WithCursor(tests[0].cursor)); err == nil {
+		t.Errorf("BuildSQL() expected error for offset with cursor")
+	}
+}
@@ -0,0 +1,359 @@
+package rql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect is the SQL dialect generated by BuildSQL.
+type Dialect int
+
+const (
+	// Postgres uses $1 placeholders and "double quoted" identifiers.
+	Postgres Dialect = iota
+	// MySQL uses ? placeholders and `backquoted` identifiers.
+	MySQL
+)
+
+// SearchMode selects how Query.Search is matched against the searchable columns.
+type SearchMode int
+
+const (
+	// FullTextSearch matches with to_tsvector/plainto_tsquery on Postgres and
+	// MATCH ... AGAINST on MySQL, which requires a FULLTEXT index over the columns.
+	FullTextSearch SearchMode = iota
+	// FuzzySearch matches columns containing the search term, case-insensitively.
+	FuzzySearch
+)
+
+// SQL holds the clauses generated from a Query. Clauses are empty when the query does not
+// use them; Args holds the values of the placeholders, in order.
+type SQL struct {
+	Where   string // e.g. `WHERE "title" ILIKE $1 AND "enabled" = $2`
+	GroupBy string // e.g. `GROUP BY "plan_name"`
+	OrderBy string // e.g. `ORDER BY "title" DESC, "created_at" ASC`
+	Limit   string // e.g. `LIMIT 50 OFFSET 20`
+	Args    []any
+}
+
+// String joins the non-empty clauses in the order they appear in a SELECT statement.
+func (s *SQL) String() string {
+	var clauses []string
+	for _, clause := range []string{s.Where, s.GroupBy, s.OrderBy, s.Limit} {
+		if clause != "" {
+			clauses = append(clauses, clause)
+		}
+	}
+	return strings.Join(clauses, " ")
+}
+
+// BuildOption configures BuildSQL.
+type BuildOption func(*builder)
+
+// WithDialect sets the SQL dialect. Defaults to Postgres.
+func WithDialect(dialect Dialect) BuildOption {
+	return func(b *builder) {
+		b.dialect = dialect
+	}
+}
+
+// WithSearchMode sets how the search term is matched. Defaults to FullTextSearch.
+func WithSearchMode(mode SearchMode) BuildOption {
+	return func(b *builder) {
+		b.searchMode = mode
+	}
+}
+
+// WithArgOffset numbers Postgres placeholders after offset arguments the caller already
+// bound, e.g. an offset of 2 makes the first placeholder $3.
+func WithArgOffset(offset int) BuildOption {
+	return func(b *builder) {
+		b.argOffset = offset
+	}
+}
+
+type builder struct {
+	dialect    Dialect
+	searchMode SearchMode
+	argOffset  int
+	cursor     *Cursor
+	args       []any
+}
+
+// BuildSQL validates the query against checkStruct (see ValidateQuery) and translates it into
+// parameterized SQL clauses. Values are only ever bound as arguments and columns only come from
+// the struct tags, so the result is safe to append to a statement.
+//
+// Columns are named after the `column` part of the rql tag, falling back to its `name` part.
+// The `searchable` part designates the columns Query.Search is matched against:
+//
+//	type Organization struct {
+//		Title     string    `rql:"name=title,type=string,searchable"`
+//		PlanName  string    `rql:"name=plan_name,type=string,column=billing_plan_name"`
+//		CreatedAt time.Time `rql:"name=created_at,type=datetime"`
+//	}
+//
+// The like, ilike, notlike and notilike operators match values containing the filter value;
+// in and notin take comma-separated values.
+func BuildSQL(q *Query, checkStruct interface{}, opts ...BuildOption) (*SQL, error) {
+	if err := ValidateQuery(q, checkStruct); err != nil {
+		return nil, err
+	}
+	b := &builder{}
+	for _, opt := range opts {
+		opt(b)
+	}
+	val := reflect.ValueOf(checkStruct)
+
+	var conditions []string
+	for _, filter := range q.Filters {
+		field := val.Type().Field(searchKeyInsideStruct(filter.Name, val))
+		condition, err := b.filter(filter, field)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if q.Search != "" {
+		condition, err := b.search(q.Search, val.Type())
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if b.cursor != nil {
+		if q.Offset > 0 {
+			return nil, errors.New("offset cannot be combined with a cursor")
+		}
+		condition, err := b.seek(q.Sort, val)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	out := &SQL{}
+	if len(conditions) > 0 {
+		out.Where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	if len(q.GroupBy) > 0 {
+		columns := make([]string, len(q.GroupBy))
+		for i, name := range q.GroupBy {
+			columns[i] = b.quote(fieldColumn(val.Type().Field(searchKeyInsideStruct(name, val))))
+		}
+		out.GroupBy = "GROUP BY " + strings.Join(columns, ", ")
+	}
+
+	if len(q.Sort) > 0 {
+		columns := make([]string, len(q.Sort))
+		for i, sort := range q.Sort {
+			column := b.quote(fieldColumn(val.Type().Field(searchKeyInsideStruct(sort.Name, val))))
+			descending := sort.Order == SORT_ORDER_DESC
+			if b.cursor != nil && b.cursor.Backward {
+				descending = !descending
+			}
+			if descending {
+				columns[i] = column + " DESC"
+			} else {
+				columns[i] = column + " ASC"
+			}
+		}
+		out.OrderBy = "ORDER BY " + strings.Join(columns, ", ")
+	}
+
+	if q.Limit < 0 || q.Offset < 0 {
+		return nil, errors.New("limit and offset must not be negative")
+	}
+	var limit []string
+	if q.Limit > 0 {
+		limit = append(limit, "LIMIT "+strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		if q.Limit == 0 && b.dialect == MySQL {
+			// MySQL has no OFFSET without LIMIT
+			limit = append(limit, "LIMIT 18446744073709551615")
+		}
+		limit = append(limit, "OFFSET "+strconv.Itoa(q.Offset))
+	}
+	out.Limit = strings.Join(limit, " ")
+
+	out.Args = b.args
+	return out, nil
+}
+
+func (b *builder) filter(filter Filter, field reflect.StructField) (string, error) {
+	column := b.quote(fieldColumn(field))
+	dataType := getDataTypeOfField(field.Tag.Get(TAG))
+
+	value := filter.Value
+	if dataType == DATATYPE_DATETIME {
+		t, err := time.Parse(time.RFC3339, filter.Value.(string))
+		if err != nil {
+			return "", fmt.Errorf("value %s for key '%s' is not a valid ISO datetime string", filter.Value, filter.Name)
+		}
+		value = t
+	}
+
+	switch filter.Operator {
+	case "eq":
+		return column + " = " + b.bind(value), nil
+	case "neq":
+		return column + " <> " + b.bind(value), nil
+	case "gt":
+		return column + " > " + b.bind(value), nil
+	case "lt":
+		return column + " < " + b.bind(value), nil
+	case "gte":
+		return column + " >= " + b.bind(value), nil
+	case "lte":
+		return column + " <= " + b.bind(value), nil
+	case "like":
+		return b.like(column, value.(string), false, false), nil
+	case "notlike":
+		return b.like(column, value.(string), false, true), nil
+	case "ilike":
+		return b.like(column, value.(string), true, false), nil
+	case "notilike":
+		return b.like(column, value.(string), true, true), nil
+	case "in", "notin":
+		var placeholders []string
+		for _, item := range strings.Split(value.(string), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				placeholders = append(placeholders, b.bind(item))
+			}
+		}
+		switch {
+		case len(placeholders) == 0 && filter.Operator == "in":
+			return "1 = 0", nil
+		case len(placeholders) == 0:
+			return "1 = 1", nil
+		case filter.Operator == "in":
+			return column + " IN (" + strings.Join(placeholders, ", ") + ")", nil
+		default:
+			return column + " NOT IN (" + strings.Join(placeholders, ", ") + ")", nil
+		}
+	case "empty":
+		return "(" + column + " IS NULL OR " + column + " = '')", nil
+	case "notempty":
+		return "(" + column + " IS NOT NULL AND " + column + " <> '')", nil
+	default:
+		return "", fmt.Errorf("operator '%s' is not supported", filter.Operator)
+	}
+}
+
+// like matches values containing value, with its wildcards escaped.
+func (b *builder) like(column, value string, insensitive, negate bool) string {
+	pattern := b.bind("%" + escapeLike(value) + "%")
+	operator := "LIKE"
+	if insensitive {
+		if b.dialect == Postgres {
+			operator = "ILIKE"
+		} else {
+			column, pattern = "LOWER("+column+")", "LOWER("+pattern+")"
+		}
+	}
+	if negate {
+		operator = "NOT " + operator
+	}
+	// Backslash is also the escape character of MySQL string literals, so it is doubled there
+	escape := `'\'`
+	if b.dialect == MySQL {
+		escape = `'\\'`
+	}
+	return column + " " + operator + " " + pattern + " ESCAPE " + escape
+}
+
+func (b *builder) search(term string, t reflect.Type) (string, error) {
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag, ok := field.Tag.Lookup(TAG); ok && hasTagPart(tag, "searchable") {
+			columns = append(columns, b.quote(fieldColumn(field)))
+		}
+	}
+	if len(columns) == 0 {
+		return "", errors.New("search is not supported: no searchable columns")
+	}
+
+	switch {
+	case b.searchMode == FuzzySearch:
+		var conditions []string
+		for _, column := range columns {
+			if b.dialect == Postgres {
+				column += "::text"
+			}
+			conditions = append(conditions, b.like(column, term, true, false))
+		}
+		return "(" + strings.Join(conditions, " OR ") + ")", nil
+	case b.dialect == MySQL:
+		return "MATCH (" + strings.Join(columns, ", ") + ") AGAINST (" + b.bind(term) + " IN NATURAL LANGUAGE MODE)", nil
+	default:
+		documents := make([]string, len(columns))
+		for i, column := range columns {
+			documents[i] = "coalesce(" + column + "::text, '')"
+		}
+		return "to_tsvector('simple', " + strings.Join(documents, " || ' ' || ") + ") @@ plainto_tsquery('simple', " + b.bind(term) + ")", nil
+	}
+}
+
+// bind adds an argument and returns its placeholder.
+func (b *builder) bind(value any) string {
+	b.args = append(b.args, value)
+	if b.dialect == MySQL {
+		return "?"
+	}
+	return "$" + strconv.Itoa(b.argOffset+len(b.args))
+}
+
+// quote quotes an identifier, and each part of a qualified one such as "o.title".
+func (b *builder) quote(identifier string) string {
+	quote := `"`
+	if b.dialect == MySQL {
+		quote = "`"
+	}
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// fieldColumn returns the column of a struct field from its rql tag: the `column` part, else
+// the `name` part, else the lowercase field name.
+func fieldColumn(field reflect.StructField) string {
+	tag := field.Tag.Get(TAG)
+	if column := tagPart(tag, "column"); column != "" {
+		return column
+	}
+	if name := tagPart(tag, "name"); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func tagPart(tag, key string) string {
+	for _, part := range strings.Split(tag, ",") {
+		if value, ok := strings.CutPrefix(part, key+"="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func hasTagPart(tag, part string) bool {
+	for _, p := range strings.Split(tag, ",") {
+		if p == part {
+			return true
+		}
+	}
+	return false
+}
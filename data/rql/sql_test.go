@@ -0,0 +1,156 @@
+package rql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Organization struct {
+	ID        int32     `rql:"name=id,type=number"`
+	Title     string    `rql:"name=title,type=string,searchable"`
+	PlanName  string    `rql:"name=plan_name,type=string,column=billing_plan_name,searchable"`
+	Enabled   bool      `rql:"name=enabled,type=bool"`
+	CreatedAt time.Time `rql:"name=created_at,type=datetime"`
+}
+
+func TestBuildSQL(t *testing.T) {
+	createdAt := time.Date(2025, 2, 5, 11, 25, 37, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		query     Query
+		opts      []BuildOption
+		wantSQL   string
+		wantArgs  []any
+		expectErr bool
+	}{
+		{
+			name:    "Empty query",
+			query:   Query{},
+			wantSQL: "",
+		},
+		{
+			name: "Comparison operators",
+			query: Query{
+				Filters: []Filter{
+					{Name: "id", Operator: "neq", Value: 20},
+					{Name: "enabled", Operator: "eq", Value: false},
+					{Name: "created_at", Operator: "gte", Value: "2025-02-05T11:25:37Z"},
+				},
+			},
+			wantSQL:  `WHERE "id" <> $1 AND "enabled" = $2 AND "created_at" >= $3`,
+			wantArgs: []any{20, false, createdAt},
+		},
+		{
+			name: "Like operators escape wildcards",
+			query: Query{
+				Filters: []Filter{
+					{Name: "title", Operator: "ilike", Value: "50%_off"},
+					{Name: "plan_name", Operator: "notlike", Value: "free"},
+				},
+			},
+			wantSQL:  `WHERE "title" ILIKE $1 ESCAPE '\' AND "billing_plan_name" NOT LIKE $2 ESCAPE '\'`,
+			wantArgs: []any{`%50\%\_off%`, "%free%"},
+		},
+		{
+			name: "In and empty operators",
+			query: Query{
+				Filters: []Filter{
+					{Name: "title", Operator: "in", Value: "nasa, isro"},
+					{Name: "plan_name", Operator: "notin", Value: ""},
+					{Name: "plan_name", Operator: "notempty", Value: ""},
+				},
+			},
+			wantSQL:  `WHERE "title" IN ($1, $2) AND 1 = 1 AND ("billing_plan_name" IS NOT NULL AND "billing_plan_name" <> '')`,
+			wantArgs: []any{"nasa", "isro"},
+		},
+		{
+			name: "Injection attempts are bound as values",
+			query: Query{
+				Filters: []Filter{
+					{Name: "title", Operator: "eq", Value: "x'; DROP TABLE organizations; --"},
+				},
+			},
+			wantSQL:  `WHERE "title" = $1`,
+			wantArgs: []any{"x'; DROP TABLE organizations; --"},
+		},
+		{
+			name:      "Unknown column",
+			query:     Query{Sort: []Sort{{Name: "title; DROP TABLE organizations", Order: "asc"}}},
+			expectErr: true,
+		},
+		{
+			name: "Full-text search with sort, group by and pagination",
+			query: Query{
+				Filters: []Filter{{Name: "enabled", Operator: "eq", Value: true}},
+				Search:  "space agency",
+				GroupBy: []string{"plan_name"},
+				Sort:    []Sort{{Name: "title", Order: "desc"}, {Name: "created_at", Order: "asc"}},
+				Limit:   50,
+				Offset:  20,
+			},
+			opts: []BuildOption{WithArgOffset(2)},
+			wantSQL: `WHERE "enabled" = $3 AND to_tsvector('simple', coalesce("title"::text, '') || ' ' || coalesce("billing_plan_name"::text, '')) @@ plainto_tsquery('simple', $4) ` +
+				`GROUP BY "billing_plan_name" ORDER BY "title" DESC, "created_at" ASC LIMIT 50 OFFSET 20`,
+			wantArgs: []any{true, "space agency"},
+		},
+		{
+			name:     "Fuzzy search",
+			query:    Query{Search: "nasa"},
+			opts:     []BuildOption{WithSearchMode(FuzzySearch)},
+			wantSQL:  `WHERE ("title"::text ILIKE $1 ESCAPE '\' OR "billing_plan_name"::text ILIKE $2 ESCAPE '\')`,
+			wantArgs: []any{"%nasa%", "%nasa%"},
+		},
+		{
+			name: "MySQL",
+			query: Query{
+				Filters: []Filter{
+					{Name: "title", Operator: "ilike", Value: "nasa"},
+					{Name: "plan_name", Operator: "in", Value: "free,pro"},
+				},
+				Search: "space",
+				Sort:   []Sort{{Name: "title", Order: "asc"}},
+				Offset: 20,
+			},
+			opts: []BuildOption{WithDialect(MySQL)},
+			wantSQL: "WHERE LOWER(`title`) LIKE LOWER(?) ESCAPE '\\\\' AND `billing_plan_name` IN (?, ?) AND MATCH (`title`, `billing_plan_name`) AGAINST (? IN NATURAL LANGUAGE MODE) " +
+				"ORDER BY `title` ASC LIMIT 18446744073709551615 OFFSET 20",
+			wantArgs: []any{"%nasa%", "free", "pro", "space"},
+		},
+		{
+			name:      "Negative limit",
+			query:     Query{Limit: -1},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BuildSQL(&tt.query, Organization{}, tt.opts...)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("BuildSQL() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if tt.expectErr {
+				return
+			}
+			if got.String() != tt.wantSQL {
+				t.Errorf("BuildSQL() sql = %s, want %s", got.String(), tt.wantSQL)
+			}
+			if !reflect.DeepEqual(got.Args, tt.wantArgs) {
+				t.Errorf("BuildSQL() args = %v, want %v", got.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestBuildSQLWithoutSearchableColumns(t *testing.T) {
+	type TestStruct struct {
+		Name string `rql:"name=name,type=string"`
+	}
+
+	_, err := BuildSQL(&Query{Search: "test"}, TestStruct{})
+	if err == nil {
+		t.Errorf("BuildSQL() expected error for search without searchable columns")
+	}
+}
@@ -6,7 +6,8 @@ import (
 
 type Config struct {
 	Driver          string        `yaml:"driver" mapstructure:"driver"`
-	URL             string        `yaml:"url" mapstructure:"url"`
+	URL             string        `yaml:"url" mapstructure:"url" secret:"true"`
+	ReplicaURLs     []string      `yaml:"replica_urls" mapstructure:"replica_urls" secret:"true"`
 	MaxIdleConns    int           `yaml:"max_idle_conns" mapstructure:"max_idle_conns" default:"10"`
 	MaxOpenConns    int           `yaml:"max_open_conns" mapstructure:"max_open_conns" default:"10"`
 	ConnMaxLifeTime time.Duration `yaml:"conn_max_life_time" mapstructure:"conn_max_life_time" default:"10ms"`
@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
-	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Client struct {
@@ -16,26 +18,131 @@ type Client struct {
 	queryTimeOut time.Duration
 	cfg          Config
 	host         string
+	poolMetrics  metric.Registration
+	replicas     *replicaSet
 }
 
-// NewClient creates a new sqlx database client
-func New(cfg Config) (*Client, error) {
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	connectRetries int
+	connectBackoff time.Duration
+	replicaHealth  []HealthOption
+}
+
+const (
+	defaultConnectBackoff = time.Second
+	maxConnectBackoff     = 30 * time.Second
+)
+
+// WithTracerProvider sets the provider of the tracer the client reports query spans to.
+// Defaults to the global tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracerProvider = provider
+	}
+}
+
+// WithMeterProvider sets the provider of the meter the client reports query durations and
+// connection pool statistics to. Defaults to the global meter provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(o *options) {
+		o.meterProvider = provider
+	}
+}
+
+// WithConnectRetry makes New retry connecting up to retries times when the database is not
+// reachable yet, e.g. while containers start up. The delay between attempts starts at backoff
+// and doubles after every attempt, up to 30s; non-positive backoffs start at 1s.
+func WithConnectRetry(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		if backoff <= 0 {
+			backoff = defaultConnectBackoff
+		}
+		o.connectRetries = retries
+		o.connectBackoff = backoff
+	}
+}
+
+// New creates a new sqlx database client. Queries run through the client, including within
+// transactions, are instrumented with OpenTelemetry: every query is reported as a span with
+// its statement stripped of literals (see SanitizeQuery) and its duration is recorded, along
+// with the statistics of the connection pool, following the database semantic conventions.
+func New(cfg Config, opts ...Option) (*Client, error) {
+	o := options{tracerProvider: otel.GetTracerProvider(), meterProvider: otel.GetMeterProvider()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	dbURL, err := url.Parse(cfg.URL)
 	if err != nil {
 		return nil, err
 	}
 	host := dbURL.Host
 
-	db, err := sqlx.Connect(cfg.Driver, cfg.URL)
+	db, poolMetrics, err := open(cfg, cfg.URL, o)
+	if err != nil {
+		return nil, err
+	}
+	if err := connect(db, o); err != nil {
+		poolMetrics.Unregister()
+		db.Close()
+		return nil, err
+	}
+
+	replicas, err := openReplicas(cfg, o)
 	if err != nil {
+		poolMetrics.Unregister()
+		db.Close()
 		return nil, err
 	}
 
+	return &Client{DB: db, queryTimeOut: cfg.MaxQueryTimeout, cfg: cfg, host: host, poolMetrics: poolMetrics, replicas: replicas}, nil
+}
+
+// open creates an instrumented connection pool for dsn, without connecting.
+func open(cfg Config, dsn string, o options) (*sqlx.DB, metric.Registration, error) {
+	dbURL, err := url.Parse(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	nodeCfg := cfg
+	nodeCfg.URL = dsn
+	in := newInstrumentation(nodeCfg, o)
+	connector, err := instrumentedConnector(cfg.Driver, dsn, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	db := sqlx.NewDb(sql.OpenDB(connector), cfg.Driver)
 	db.SetMaxIdleConns(cfg.MaxIdleConns)
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
 	db.SetConnMaxLifetime(cfg.ConnMaxLifeTime)
 
-	return &Client{DB: db, queryTimeOut: cfg.MaxQueryTimeout, cfg: cfg, host: host}, err
+	poolMetrics, err := in.observePool(db.DB, dbURL.Host+dbURL.Path)
+	if err != nil {
+		db.Close()
+		return nil, nil, fmt.Errorf("register pool metrics: %w", err)
+	}
+	return db, poolMetrics, nil
+}
+
+// connect pings the database, retrying with backoff as configured by WithConnectRetry.
+func connect(db *sqlx.DB, o options) error {
+	backoff := o.connectBackoff
+	for attempt := 0; ; attempt++ {
+		err := db.Ping()
+		if err == nil || attempt >= o.connectRetries {
+			if err != nil && attempt > 0 {
+				return fmt.Errorf("connect after %d attempts: %w", attempt+1, err)
+			}
+			return err
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
 }
 
 func (c Client) WithTimeout(ctx context.Context, op func(ctx context.Context) error) (err error) {
@@ -45,35 +152,22 @@ func (c Client) WithTimeout(ctx context.Context, op func(ctx context.Context) er
 	return op(ctxWithTimeout)
 }
 
-func (c Client) WithTxn(ctx context.Context, txnOptions sql.TxOptions, txFunc func(*sqlx.Tx) error) (err error) {
-	txn, err := c.BeginTxx(ctx, &txnOptions)
-	if err != nil {
-		return err
+// WithTxn runs txFunc in a transaction, which is committed if txFunc returns nil and rolled
+// back otherwise. The transaction always runs on the primary, so reads that must see its
+// writes, or must not lag behind them, use tx rather than Reader. When ctx belongs to a
+// transaction started by RunInTx, txFunc joins it within a savepoint, like a nested RunInTx.
+//
+// txFunc does not receive a context carrying tx, so Querier and Reader called with ctx do not
+// see the transaction: pass tx to the code that must join it, or use RunInTx, whose context
+// repositories join through Querier. See RunInTx for retries as well.
+func (c Client) WithTxn(ctx context.Context, txnOptions sql.TxOptions, txFunc func(*sqlx.Tx) error) error {
+	fn := func(_ context.Context, tx *sqlx.Tx) error {
+		return txFunc(tx)
 	}
-
-	defer func() {
-		if p := recover(); p != nil {
-			switch p := p.(type) {
-			case error:
-				err = p
-			default:
-				err = errors.Errorf("%s", p)
-			}
-			err = txn.Rollback()
-			panic(p)
-		} else if err != nil {
-			if rlbErr := txn.Rollback(); rlbErr != nil {
-				err = fmt.Errorf("rollback error: %s while executing: %w", rlbErr, err)
-			} else {
-				err = fmt.Errorf("rollback: %w", err)
-			}
-		} else {
-			err = txn.Commit()
-		}
-	}()
-
-	err = txFunc(txn)
-	return err
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return runInSavepoint(ctx, state, fn)
+	}
+	return c.runTx(ctx, txnOptions, fn)
 }
 
 // ConnectionURL fetch the database connection url
@@ -88,5 +182,13 @@ func (c *Client) Host() string {
 
 // Close closes the database connection
 func (c *Client) Close() error {
+	if c.replicas != nil {
+		c.replicas.close()
+	}
+	if c.poolMetrics != nil {
+		if err := c.poolMetrics.Unregister(); err != nil {
+			otel.Handle(err)
+		}
+	}
 	return c.DB.Close()
 }
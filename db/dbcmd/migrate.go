@@ -0,0 +1,315 @@
+// Package dbcmd provides a ready-made cobra command group managing database migrations
+// with db.Migrator.
+package dbcmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"reflect"
+	"strconv"
+
+	"github.com/raystack/salt/cli/printer"
+	"github.com/raystack/salt/config"
+	"github.com/raystack/salt/db"
+	"github.com/spf13/cobra"
+)
+
+// Option configures the migrate command group.
+type Option func(*options)
+
+type options struct {
+	loader        *config.Loader
+	configKey     string
+	migrationsDir string
+	migratorOpts  []db.MigratorOption
+}
+
+// WithLoader sets the loader the database configuration is read from. By default, it is read
+// from the file given with the --config flag and from environment variables.
+func WithLoader(loader *config.Loader) Option {
+	return func(o *options) {
+		o.loader = loader
+	}
+}
+
+// WithConfigKey sets the top-level configuration key holding the db.Config. Defaults to "db".
+func WithConfigKey(key string) Option {
+	return func(o *options) {
+		o.configKey = key
+	}
+}
+
+// WithMigrationsDir sets the directory `migrate create` writes new migration files to.
+// Defaults to the resource path of the migrations.
+func WithMigrationsDir(dir string) Option {
+	return func(o *options) {
+		o.migrationsDir = dir
+	}
+}
+
+// WithMigratorOptions sets options of the db.Migrator, e.g. db.WithLockTimeout.
+func WithMigratorOptions(opts ...db.MigratorOption) Option {
+	return func(o *options) {
+		o.migratorOpts = append(o.migratorOpts, opts...)
+	}
+}
+
+// MigrateCmd returns the `migrate` command with the up, down, status, goto, force and create
+// subcommands, managing the migrations found in resourcePath of the migrations file system.
+//
+// Example:
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	rootCmd.AddCommand(dbcmd.MigrateCmd(migrations, "migrations"))
+func MigrateCmd(migrations fs.FS, resourcePath string, opts ...Option) *cobra.Command {
+	o := &options{configKey: "db", migrationsDir: resourcePath}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	var configFile string
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database migrations",
+	}
+	if o.loader == nil {
+		cmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "Path to the configuration file")
+	}
+
+	newMigrator := func() (*db.Migrator, error) {
+		loader := o.loader
+		if loader == nil {
+			var loaderOpts []config.Option
+			if configFile != "" {
+				loaderOpts = append(loaderOpts, config.WithFile(configFile))
+			}
+			loader = config.NewLoader(loaderOpts...)
+		}
+		cfg, err := loadConfig(loader, o.configKey)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewMigrator(cfg, migrations, resourcePath, o.migratorOpts...), nil
+	}
+
+	cmd.AddCommand(
+		upCmd(newMigrator),
+		downCmd(newMigrator),
+		statusCmd(newMigrator),
+		gotoCmd(newMigrator),
+		forceCmd(newMigrator),
+		createCmd(o.migrationsDir),
+	)
+	return cmd
+}
+
+// loadConfig loads the db.Config found under the top-level key.
+func loadConfig(loader *config.Loader, key string) (db.Config, error) {
+	structType := reflect.StructOf([]reflect.StructField{{
+		Name: "DB",
+		Type: reflect.TypeOf(db.Config{}),
+		Tag:  reflect.StructTag(fmt.Sprintf(`mapstructure:"%s"`, key)),
+	}})
+	cfg := reflect.New(structType)
+	if err := loader.Load(cfg.Interface()); err != nil {
+		return db.Config{}, fmt.Errorf("failed to load database configuration: %w", err)
+	}
+	dbConfig := cfg.Elem().Field(0).Interface().(db.Config)
+	if dbConfig.URL == "" {
+		return db.Config{}, fmt.Errorf("database url is not configured (%s.url)", key)
+	}
+	return dbConfig, nil
+}
+
+func upCmd(newMigrator func() (*db.Migrator, error)) *cobra.Command {
+	var dryRun bool
+	cmd := &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newMigrator()
+			if err != nil {
+				return err
+			}
+			if dryRun {
+				pending, err := m.Pending()
+				if err != nil {
+					return err
+				}
+				if len(pending) == 0 {
+					printer.Successln("No pending migrations")
+					return nil
+				}
+				rows := [][]string{{"VERSION", "NAME"}}
+				for _, f := range pending {
+					rows = append(rows, []string{strconv.FormatUint(uint64(f.Version), 10), f.Name})
+				}
+				printer.Table(cmd.OutOrStdout(), rows)
+				return nil
+			}
+
+			if err := m.Up(); err != nil {
+				return err
+			}
+			return printVersion(m)
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "List pending migrations without applying them")
+	return cmd
+}
+
+func downCmd(newMigrator func() (*db.Migrator, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "down [N]",
+		Short: "Roll back the last N migrations (default 1)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps := 1
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return fmt.Errorf("invalid number of migrations: %s", args[0])
+				}
+				steps = n
+			}
+			m, err := newMigrator()
+			if err != nil {
+				return err
+			}
+			if err := m.Down(steps); err != nil {
+				return err
+			}
+			return printVersion(m)
+		},
+	}
+}
+
+func statusCmd(newMigrator func() (*db.Migrator, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the applied and pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			m, err := newMigrator()
+			if err != nil {
+				return err
+			}
+			status, err := m.Status()
+			if err != nil {
+				return err
+			}
+			files, err := m.Files()
+			if err != nil {
+				return err
+			}
+
+			rows := [][]string{{"VERSION", "NAME", "STATE"}}
+			for _, f := range files {
+				state := "applied"
+				switch {
+				case f.Version > status.Version:
+					state = "pending"
+				case f.Version == status.Version && status.Dirty:
+					state = "dirty"
+				}
+				rows = append(rows, []string{strconv.FormatUint(uint64(f.Version), 10), f.Name, state})
+			}
+			printer.Table(cmd.OutOrStdout(), rows)
+			printer.Space()
+			printStatus(status)
+			return nil
+		},
+	}
+}
+
+func gotoCmd(newMigrator func() (*db.Migrator, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "goto VERSION",
+		Short: "Migrate up or down to a version",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.ParseUint(args[0], 10, 0)
+			if err != nil {
+				return fmt.Errorf("invalid version: %s", args[0])
+			}
+			m, err := newMigrator()
+			if err != nil {
+				return err
+			}
+			if err := m.Goto(uint(version)); err != nil {
+				return err
+			}
+			return printVersion(m)
+		},
+	}
+}
+
+func forceCmd(newMigrator func() (*db.Migrator, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "force VERSION",
+		Short: "Set the version and clear the dirty state without migrating",
+		Long: "Set the version of the database and clear its dirty state without running any migration.\n" +
+			"Use it after fixing a failed migration manually. Use `force -- -1` to mark no migration as applied.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.Atoi(args[0])
+			if err != nil || version < -1 {
+				return fmt.Errorf("invalid version: %s", args[0])
+			}
+			m, err := newMigrator()
+			if err != nil {
+				return err
+			}
+			if err := m.Force(version); err != nil {
+				return err
+			}
+			return printVersion(m)
+		},
+	}
+}
+
+func createCmd(dir string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create empty up and down migration files",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if dir == "" {
+				return errors.New("no migrations directory specified")
+			}
+			up, down, err := db.CreateMigration(dir, args[0])
+			if err != nil {
+				return err
+			}
+			printer.Successln("Created " + up)
+			printer.Successln("Created " + down)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&dir, "dir", dir, "Directory to write the migration files to")
+	return cmd
+}
+
+func printVersion(m *db.Migrator) error {
+	status, err := m.Status()
+	if err != nil {
+		return err
+	}
+	printStatus(status)
+	return nil
+}
+
+func printStatus(status *db.MigrationStatus) {
+	switch {
+	case status.Dirty:
+		printer.Errorf("Database is dirty at version %d, fix it manually and run `migrate force`\n", status.Version)
+	case len(status.Pending) > 0:
+		printer.Warningf("Database is at version %d, %d migration(s) pending\n", status.Version, len(status.Pending))
+	default:
+		printer.Successf("Database is at version %d, up to date\n", status.Version)
+	}
+}
@@ -0,0 +1,66 @@
+package dbcmd_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/raystack/salt/config"
+	"github.com/raystack/salt/db/dbcmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var migrations = fstest.MapFS{
+	"migrations/1481574547_create_users_table.up.sql":   {Data: []byte("CREATE TABLE users (id VARCHAR(36))")},
+	"migrations/1481574547_create_users_table.down.sql": {Data: []byte("DROP TABLE users")},
+}
+
+func TestMigrateCmdTree(t *testing.T) {
+	cmd := dbcmd.MigrateCmd(migrations, "migrations")
+
+	var names []string
+	for _, sub := range cmd.Commands() {
+		names = append(names, sub.Name())
+	}
+	assert.ElementsMatch(t, []string{"up", "down", "status", "goto", "force", "create"}, names)
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("config"))
+}
+
+func TestMigrateCreate(t *testing.T) {
+	dir := t.TempDir()
+	cmd := dbcmd.MigrateCmd(migrations, "migrations", dbcmd.WithMigrationsDir(dir))
+	cmd.SetArgs([]string{"create", "add users email"})
+	require.NoError(t, cmd.Execute())
+
+	up, err := filepath.Glob(filepath.Join(dir, "*_add_users_email.up.sql"))
+	require.NoError(t, err)
+	assert.Len(t, up, 1)
+	down, err := filepath.Glob(filepath.Join(dir, "*_add_users_email.down.sql"))
+	require.NoError(t, err)
+	assert.Len(t, down, 1)
+}
+
+func TestMigrateRequiresDatabaseURL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("database:\n  driver: postgres\n"), 0644))
+
+	cmd := dbcmd.MigrateCmd(migrations, "migrations",
+		dbcmd.WithLoader(config.NewLoader(config.WithFile(path))),
+		dbcmd.WithConfigKey("database"))
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	cmd.SetArgs([]string{"status"})
+	assert.ErrorContains(t, cmd.Execute(), "database url is not configured (database.url)")
+}
+
+func TestMigrateInvalidArguments(t *testing.T) {
+	for _, args := range [][]string{{"down", "zero"}, {"goto", "-3"}, {"force", "abc"}} {
+		cmd := dbcmd.MigrateCmd(migrations, "migrations")
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		cmd.SetArgs(args)
+		assert.Error(t, cmd.Execute(), args)
+	}
+}
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	defaultHealthInterval = 10 * time.Second
+	defaultHealthTimeout  = 2 * time.Second
+)
+
+// Pinger is implemented by *Client, *sqlx.DB and *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// HealthOption configures a HealthChecker.
+type HealthOption func(*HealthChecker)
+
+// WithHealthInterval sets how often the database is pinged. Defaults to 10s; non-positive
+// intervals are ignored.
+func WithHealthInterval(interval time.Duration) HealthOption {
+	return func(h *HealthChecker) {
+		if interval > 0 {
+			h.interval = interval
+		}
+	}
+}
+
+// WithHealthTimeout sets the timeout of a ping. Defaults to 2s; non-positive timeouts are
+// ignored.
+func WithHealthTimeout(timeout time.Duration) HealthOption {
+	return func(h *HealthChecker) {
+		if timeout > 0 {
+			h.timeout = timeout
+		}
+	}
+}
+
+// WithFailureThreshold sets how many consecutive pings must fail before the database is
+// reported unhealthy. Defaults to 1.
+func WithFailureThreshold(threshold int) HealthOption {
+	return func(h *HealthChecker) {
+		h.threshold = threshold
+	}
+}
+
+// WithHealthErrorDetails includes the error of the last ping in the responses of ServeHTTP.
+// Ping errors may reveal hosts, users or database names, so they are left out by default;
+// LastError returns them regardless.
+func WithHealthErrorDetails() HealthOption {
+	return func(h *HealthChecker) {
+		h.errorDetails = true
+	}
+}
+
+// HealthChecker pings a database in the background and reports whether it is healthy, e.g.
+// for readiness probes. It serves HTTP probes as an http.Handler and gRPC probes through
+// NewGRPCHealthServer.
+//
+// Example:
+//
+//	checker := db.NewHealthChecker(client)
+//	checker.Start()
+//	defer checker.Stop()
+//
+//	httpMux.Handle("/ready", checker)
+type HealthChecker struct {
+	pinger       Pinger
+	interval     time.Duration
+	timeout      time.Duration
+	threshold    int
+	errorDetails bool
+
+	mu        sync.RWMutex
+	healthy   bool
+	lastErr   error
+	failures  int
+	listeners []func(healthy bool)
+
+	startOnce sync.Once
+	started   bool
+	stopOnce  sync.Once
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewHealthChecker creates a HealthChecker for the database. It reports the database
+// unhealthy until the first check succeeds.
+func NewHealthChecker(pinger Pinger, opts ...HealthOption) *HealthChecker {
+	h := &HealthChecker{
+		pinger:    pinger,
+		interval:  defaultHealthInterval,
+		timeout:   defaultHealthTimeout,
+		threshold: 1,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Start checks the database immediately, then in the background at every interval until Stop
+// is called.
+func (h *HealthChecker) Start() {
+	h.startOnce.Do(func() {
+		h.started = true
+		_ = h.Check(context.Background())
+		go h.run()
+	})
+}
+
+func (h *HealthChecker) run() {
+	defer close(h.done)
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stop:
+			return
+		case <-ticker.C:
+			_ = h.Check(context.Background())
+		}
+	}
+}
+
+// Stop stops the background checks started by Start and waits for them to return.
+func (h *HealthChecker) Stop() {
+	h.stopOnce.Do(func() {
+		close(h.stop)
+	})
+	// Prevents a later Start and waits for a concurrent one
+	h.startOnce.Do(func() {})
+	if h.started {
+		<-h.done
+	}
+}
+
+// Check pings the database now, records the result and returns the ping error.
+func (h *HealthChecker) Check(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	err := h.pinger.PingContext(ctx)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	wasHealthy := h.healthy
+	h.lastErr = err
+	if err == nil {
+		h.failures = 0
+		h.healthy = true
+	} else if h.failures++; h.failures >= h.threshold {
+		h.healthy = false
+	}
+	if h.healthy != wasHealthy {
+		for _, listener := range h.listeners {
+			listener(h.healthy)
+		}
+	}
+	return err
+}
+
+// Healthy reports whether the database was reachable at the last checks.
+func (h *HealthChecker) Healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.healthy
+}
+
+// LastError returns the error of the last check, nil if it succeeded.
+func (h *HealthChecker) LastError() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.lastErr
+}
+
+// onChange calls listener with the current state, then with the new state whenever the health
+// changes. Listeners are called with the lock held and must not call the checker.
+func (h *HealthChecker) onChange(listener func(healthy bool)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, listener)
+	listener(h.healthy)
+}
+
+// ServeHTTP responds with 200 when the database is healthy and 503 otherwise, along with a
+// JSON body such as {"status":"unavailable"}. The body carries the ping error as well, e.g.
+// {"status":"unavailable","error":"..."}, with WithHealthErrorDetails.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	body := map[string]string{"status": "ok"}
+	code := http.StatusOK
+	if !h.Healthy() {
+		body["status"] = "unavailable"
+		if err := h.LastError(); err != nil && h.errorDetails {
+			body["error"] = err.Error()
+		}
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// NewGRPCHealthServer returns a gRPC health service reporting service as serving while the
+// database is healthy. Register it on a gRPC server with healthpb.RegisterHealthServer.
+func NewGRPCHealthServer(service string, h *HealthChecker) *health.Server {
+	server := health.NewServer()
+	setStatus := func(healthy bool) {
+		status := healthpb.HealthCheckResponse_NOT_SERVING
+		if healthy {
+			status = healthpb.HealthCheckResponse_SERVING
+		}
+		server.SetServingStatus(service, status)
+	}
+	h.onChange(setStatus)
+	return server
+}
@@ -1,43 +1,263 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database"
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
 )
 
-func RunMigrations(config Config, embeddedMigrations fs.FS, resourcePath string) error {
-	m, err := getMigrationInstance(config, embeddedMigrations, resourcePath)
-	if err != nil {
-		return err
+// MigrationFile identifies a migration by its version and name, e.g. 1481574547 and
+// "create_users_table" for 1481574547_create_users_table.up.sql.
+type MigrationFile struct {
+	Version uint
+	Name    string
+}
+
+// MigrationStatus describes the migration state of a database.
+type MigrationStatus struct {
+	// Version is the version of the last applied migration, 0 if none was applied.
+	Version uint
+	// Dirty reports that the last migration failed midway; it must be fixed manually and
+	// the version set with Force before migrating again.
+	Dirty bool
+	// Pending lists the migrations that are not applied yet, in order.
+	Pending []MigrationFile
+}
+
+// MigratorOption configures a Migrator.
+type MigratorOption func(*Migrator)
+
+// WithLockTimeout sets how long a migration waits for the database lock held by a concurrent
+// migration, e.g. of another replica starting up. Defaults to 15s.
+func WithLockTimeout(timeout time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTimeout = timeout
+	}
+}
+
+// WithMigrationsTable sets the table recording the applied migrations, so that independent sets
+// of migrations, such as the outbox migrations, can be applied to the same database. Defaults to
+// schema_migrations.
+func WithMigrationsTable(table string) MigratorOption {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// Migrator manages the migrations of a database. Every operation that changes the database
+// holds a database-level advisory lock (pg_advisory_lock on Postgres, GET_LOCK on MySQL), so
+// that replicas migrating concurrently on startup apply each migration once.
+type Migrator struct {
+	cfg          Config
+	migrations   fs.FS
+	resourcePath string
+	lockTimeout  time.Duration
+	table        string
+}
+
+// NewMigrator creates a Migrator for the migrations found in resourcePath of the
+// migrations file system, typically an embed.FS.
+func NewMigrator(cfg Config, migrations fs.FS, resourcePath string, opts ...MigratorOption) *Migrator {
+	m := &Migrator{
+		cfg:          cfg,
+		migrations:   migrations,
+		resourcePath: resourcePath,
+		lockTimeout:  migrate.DefaultLockTimeout,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	return m.run(func(mg *migrate.Migrate) error {
+		return mg.Up()
+	})
+}
+
+// Down rolls back the given number of applied migrations, which must be at least 1.
+func (m *Migrator) Down(steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("db migrator: steps must be at least 1, got %d", steps)
 	}
+	return m.run(func(mg *migrate.Migrate) error {
+		return mg.Steps(-steps)
+	})
+}
+
+// Goto migrates up or down to the given version.
+func (m *Migrator) Goto(version uint) error {
+	return m.run(func(mg *migrate.Migrate) error {
+		return mg.Migrate(version)
+	})
+}
+
+// Force sets the version of the database and clears its dirty state without running any
+// migration. Use it after fixing a failed migration manually; -1 marks the database as having
+// no migration applied.
+func (m *Migrator) Force(version int) error {
+	return m.run(func(mg *migrate.Migrate) error {
+		return mg.Force(version)
+	})
+}
 
-	err = m.Up()
-	if err == migrate.ErrNoChange || err == nil {
+// Status returns the current version of the database and the pending migrations.
+func (m *Migrator) Status() (*MigrationStatus, error) {
+	status := &MigrationStatus{}
+	err := m.run(func(mg *migrate.Migrate) error {
+		version, dirty, err := mg.Version()
+		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+			return err
+		}
+		status.Version, status.Dirty = version, dirty
 		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return err
+	files, err := m.Files()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.Version > status.Version {
+			status.Pending = append(status.Pending, f)
+		}
+	}
+	return status, nil
 }
 
-func RunRollback(config Config, embeddedMigrations fs.FS, resourcePath string) error {
-	m, err := getMigrationInstance(config, embeddedMigrations, resourcePath)
+// Pending lists the migrations Up would apply, without applying them.
+func (m *Migrator) Pending() ([]MigrationFile, error) {
+	status, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	return status.Pending, nil
+}
+
+// Files lists all migrations, in order.
+func (m *Migrator) Files() ([]MigrationFile, error) {
+	src, err := iofs.New(m.migrations, m.resourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("db migrator: %v", err)
+	}
+	defer src.Close()
+
+	var files []MigrationFile
+	version, err := src.First()
+	for err == nil {
+		files = append(files, MigrationFile{Version: version, Name: migrationName(src, version)})
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("db migrator: %v", err)
+	}
+	return files, nil
+}
+
+func migrationName(src source.Driver, version uint) string {
+	r, name, err := src.ReadUp(version)
+	if err != nil {
+		r, name, err = src.ReadDown(version)
+	}
+	if err != nil {
+		return ""
+	}
+	r.Close()
+	return name
+}
+
+// run runs op on a migrate instance, treating ErrNoChange as success.
+func (m *Migrator) run(op func(mg *migrate.Migrate) error) error {
+	cfg := m.cfg
+	if m.table != "" {
+		separator := "?"
+		if strings.Contains(cfg.URL, "?") {
+			separator = "&"
+		}
+		cfg.URL += separator + "x-migrations-table=" + url.QueryEscape(m.table)
+	}
+	mg, err := getMigrationInstance(cfg, m.migrations, m.resourcePath)
 	if err != nil {
 		return err
 	}
+	defer mg.Close()
+	mg.LockTimeout = m.lockTimeout
 
-	err = m.Steps(-1)
-	if err == migrate.ErrNoChange || err == nil {
+	err = op(mg)
+	var dirty migrate.ErrDirty
+	switch {
+	case err == nil, errors.Is(err, migrate.ErrNoChange):
 		return nil
+	case errors.As(err, &dirty):
+		return fmt.Errorf("db migrator: database is dirty at version %d, fix it manually and force the version: %w", dirty.Version, err)
+	case errors.Is(err, migrate.ErrLockTimeout):
+		return fmt.Errorf("db migrator: another migration is in progress: %w", err)
+	default:
+		return err
 	}
+}
 
-	return err
+var migrationNameSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// CreateMigration writes empty up and down files for a new migration named after name and
+// the current Unix time into dir, e.g. 1700000000_add_users_email.up.sql, and returns their paths.
+func CreateMigration(dir, name string) (up, down string, err error) {
+	name = strings.Trim(migrationNameSanitizer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", errors.New("db migrator: migration name is empty")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("db migrator: %w", err)
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%d_%s", time.Now().Unix(), name))
+	up, down = base+".up.sql", base+".down.sql"
+	if err := createEmptyFile(up); err != nil {
+		return "", "", fmt.Errorf("db migrator: %w", err)
+	}
+	if err := createEmptyFile(down); err != nil {
+		// Do not leave a migration without its down file behind
+		os.Remove(up)
+		return "", "", fmt.Errorf("db migrator: %w", err)
+	}
+	return up, down, nil
+}
+
+func createEmptyFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// RunMigrations applies all pending migrations. See Migrator for more operations.
+func RunMigrations(config Config, embeddedMigrations fs.FS, resourcePath string) error {
+	return NewMigrator(config, embeddedMigrations, resourcePath).Up()
+}
+
+// RunRollback rolls back the last applied migration. See Migrator for more operations.
+func RunRollback(config Config, embeddedMigrations fs.FS, resourcePath string) error {
+	return NewMigrator(config, embeddedMigrations, resourcePath).Down(1)
 }
 
 func getMigrationInstance(config Config, embeddedMigrations fs.FS, resourcePath string) (*migrate.Migrate, error) {
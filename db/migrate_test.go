@@ -4,10 +4,14 @@ import (
 	"embed"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/raystack/salt/db"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //go:embed migrations/*.sql
@@ -58,3 +62,85 @@ func TestRunRollback(t *testing.T) {
 	result.Scan(&tableExist)
 	assert.Equal(t, false, tableExist)
 }
+
+func TestMigratorStatusAndGoto(t *testing.T) {
+	if _, err := client.Exec(dropTableQuery); err != nil {
+		log.Fatalf("Could not cleanup: %s", err)
+	}
+	pgConfig := db.Config{
+		Driver: "postgres",
+		URL:    fmt.Sprintf(dsn, user, password, port, database),
+	}
+	migrator := db.NewMigrator(pgConfig, migrationFs, "migrations", db.WithLockTimeout(5*time.Second))
+	assert.NoError(t, migrator.Force(-1))
+
+	pending, err := migrator.Pending()
+	assert.NoError(t, err)
+	assert.Equal(t, []db.MigrationFile{{Version: 1481574547, Name: "create_users_table"}}, pending)
+
+	assert.NoError(t, migrator.Goto(1481574547))
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Equal(t, &db.MigrationStatus{Version: 1481574547}, status)
+
+	// Going to the current version is not an error
+	assert.NoError(t, migrator.Goto(1481574547))
+
+	assert.NoError(t, migrator.Down(1))
+	status, err = migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, status.Pending, 1)
+
+	// Steps below 1 would migrate up or do nothing
+	assert.Error(t, migrator.Down(0))
+	assert.Error(t, migrator.Down(-1))
+}
+
+func TestMigratorForceDirty(t *testing.T) {
+	pgConfig := db.Config{
+		Driver: "postgres",
+		URL:    fmt.Sprintf(dsn, user, password, port, database),
+	}
+	migrator := db.NewMigrator(pgConfig, migrationFs, "migrations")
+
+	// Simulate a migration that failed midway
+	assert.NoError(t, migrator.Force(1481574547))
+	_, err := client.Exec("UPDATE schema_migrations SET dirty = true")
+	assert.NoError(t, err)
+	err = migrator.Up()
+	assert.ErrorContains(t, err, "dirty")
+
+	assert.NoError(t, migrator.Force(-1))
+	status, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.False(t, status.Dirty)
+	assert.Equal(t, uint(0), status.Version)
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	up, down, err := db.CreateMigration(dir, "Add users email")
+	assert.NoError(t, err)
+	assert.Regexp(t, `/\d+_add_users_email\.up\.sql$`, up)
+	assert.Regexp(t, `/\d+_add_users_email\.down\.sql$`, down)
+	assert.FileExists(t, up)
+	assert.FileExists(t, down)
+
+	_, _, err = db.CreateMigration(dir, "  ")
+	assert.Error(t, err)
+}
+
+func TestCreateMigrationRemovesUpFileOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	// Occupy the down file paths of the next few seconds
+	now := time.Now().Unix()
+	for ts := now; ts < now+3; ts++ {
+		require.NoError(t, os.Mkdir(filepath.Join(dir, fmt.Sprintf("%d_add_users_email.down.sql", ts)), 0755))
+	}
+
+	_, _, err := db.CreateMigration(dir, "add users email")
+	assert.Error(t, err)
+	ups, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	require.NoError(t, err)
+	assert.Empty(t, ups)
+}
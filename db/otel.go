@@ -0,0 +1,558 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Refer OpenTelemetry Semantic Conventions for Database Clients.
+// https://github.com/open-telemetry/semantic-conventions/blob/main/docs/database/database-metrics.md
+const (
+	instrumentationName          = "github.com/raystack/salt/db"
+	metricOperationDuration      = "db.client.operation.duration"
+	metricConnectionCount        = "db.client.connection.count"
+	metricConnectionMax          = "db.client.connection.max"
+	metricConnectionWaitCount    = "db.client.connection.wait_count"
+	metricConnectionWaitDuration = "db.client.connection.wait_duration"
+	attributeDBSystem            = "db.system"
+	attributeDBName              = "db.namespace"
+	attributeDBOperation         = "db.operation.name"
+	attributeDBStatement         = "db.query.text"
+	attributeServerAddress       = "server.address"
+	attributeServerPort          = "server.port"
+	attributeErrorType           = "error.type"
+	attributeConnectionState     = "db.client.connection.state"
+	attributeConnectionPoolName  = "db.client.connection.pool.name"
+	connectionStateIdle          = "idle"
+	connectionStateUsed          = "used"
+	operationBegin               = "BEGIN"
+	operationCommit              = "COMMIT"
+	operationRollback            = "ROLLBACK"
+)
+
+// instrumentation emits a span and a duration measurement for every database operation.
+type instrumentation struct {
+	tracer     trace.Tracer
+	meter      metric.Meter
+	duration   metric.Float64Histogram
+	attributes []attribute.KeyValue
+	// postgres selects the PostgreSQL rules for quoting in sanitizeQuery.
+	postgres bool
+}
+
+func newInstrumentation(cfg Config, o options) *instrumentation {
+	meter := o.meterProvider.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram(metricOperationDuration,
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of database client operations."))
+	handleOtelErr(err)
+
+	return &instrumentation{
+		tracer:     o.tracerProvider.Tracer(instrumentationName),
+		meter:      meter,
+		duration:   duration,
+		attributes: connectionAttributes(cfg),
+		postgres:   dbSystem(cfg.Driver) == "postgresql",
+	}
+}
+
+// connectionAttributes describes the database a client connects to, without credentials.
+func connectionAttributes(cfg Config) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{attribute.String(attributeDBSystem, dbSystem(cfg.Driver))}
+	dbURL, err := url.Parse(cfg.URL)
+	if err != nil {
+		return attrs
+	}
+	if name := strings.TrimPrefix(dbURL.Path, "/"); name != "" {
+		attrs = append(attrs, attribute.String(attributeDBName, name))
+	}
+	if host := dbURL.Hostname(); host != "" {
+		attrs = append(attrs, attribute.String(attributeServerAddress, host))
+	}
+	if port := dbURL.Port(); port != "" {
+		attrs = append(attrs, attribute.String(attributeServerPort, port))
+	}
+	return attrs
+}
+
+func dbSystem(driverName string) string {
+	switch driverName {
+	case "postgres", "pgx", "cloudsqlpostgres":
+		return "postgresql"
+	case "mysql":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	default:
+		return driverName
+	}
+}
+
+// record runs op and reports it as a span named after the operation of the query, along with
+// its duration. The span is started once op returns, so that operations the driver skips
+// (driver.ErrSkip), which database/sql retries another way, are not reported twice.
+func (in *instrumentation) record(ctx context.Context, query string, op func() error) error {
+	start := time.Now()
+	err := op()
+	if errors.Is(err, driver.ErrSkip) {
+		return err
+	}
+	end := time.Now()
+
+	operation := queryOperation(query)
+	attrs := make([]attribute.KeyValue, len(in.attributes), len(in.attributes)+2)
+	copy(attrs, in.attributes)
+	if operation != "" {
+		attrs = append(attrs, attribute.String(attributeDBOperation, operation))
+	}
+	if err != nil {
+		attrs = append(attrs, attribute.String(attributeErrorType, errorType(err)))
+	}
+	in.duration.Record(ctx, end.Sub(start).Seconds(), metric.WithAttributes(attrs...))
+
+	spanName := operation
+	if spanName == "" {
+		spanName = "db.query"
+	}
+	_, span := in.tracer.Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(start),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(attribute.String(attributeDBStatement, sanitizeQuery(query, in.postgres))))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+	return err
+}
+
+// observePool reports the connection pool statistics of db as asynchronous metrics.
+func (in *instrumentation) observePool(db *sql.DB, poolName string) (metric.Registration, error) {
+	count, err := in.meter.Int64ObservableUpDownCounter(metricConnectionCount,
+		metric.WithUnit("{connection}"),
+		metric.WithDescription("Number of connections that are currently in the state described by the state attribute."))
+	if err != nil {
+		return nil, err
+	}
+	maxOpen, err := in.meter.Int64ObservableUpDownCounter(metricConnectionMax,
+		metric.WithUnit("{connection}"),
+		metric.WithDescription("Maximum number of open connections allowed."))
+	if err != nil {
+		return nil, err
+	}
+	waitCount, err := in.meter.Int64ObservableCounter(metricConnectionWaitCount,
+		metric.WithUnit("{wait}"),
+		metric.WithDescription("Total number of connections waited for."))
+	if err != nil {
+		return nil, err
+	}
+	waitDuration, err := in.meter.Float64ObservableCounter(metricConnectionWaitDuration,
+		metric.WithUnit("s"),
+		metric.WithDescription("Total time blocked waiting for a new connection."))
+	if err != nil {
+		return nil, err
+	}
+
+	pool := attribute.String(attributeConnectionPoolName, poolName)
+	idle := metric.WithAttributes(pool, attribute.String(attributeConnectionState, connectionStateIdle))
+	used := metric.WithAttributes(pool, attribute.String(attributeConnectionState, connectionStateUsed))
+	withPool := metric.WithAttributes(pool)
+	return in.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := db.Stats()
+		o.ObserveInt64(count, int64(stats.Idle), idle)
+		o.ObserveInt64(count, int64(stats.InUse), used)
+		o.ObserveInt64(maxOpen, int64(stats.MaxOpenConnections), withPool)
+		o.ObserveInt64(waitCount, stats.WaitCount, withPool)
+		o.ObserveFloat64(waitDuration, stats.WaitDuration.Seconds(), withPool)
+		return nil
+	}, count, maxOpen, waitCount, waitDuration)
+}
+
+// queryOperation returns the first keyword of a query in upper case, e.g. "SELECT".
+func queryOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimRight(fields[0], ";("))
+}
+
+func errorType(err error) string {
+	if IsRetryable(err) {
+		return "retryable"
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "timeout"
+	}
+	if errors.Is(err, context.Canceled) {
+		return "canceled"
+	}
+	return "_OTHER"
+}
+
+// SanitizeQuery replaces the string and numeric literals of a query with "?" so that it can be
+// recorded without leaking values. Placeholders such as $1 or ? are kept. Strings may escape
+// quotes by doubling them or with a backslash, as in MySQL and PostgreSQL E'...' strings, and
+// PostgreSQL dollar-quoted strings ($$...$$, $tag$...$tag$) are replaced as a whole. Text in
+// double quotes is replaced too, as MySQL reads it as a string. The instrumentation of
+// PostgreSQL clients follows its rules instead: double quotes are kept as quoted identifiers
+// and backslashes only escape within E'...' strings.
+func SanitizeQuery(query string) string {
+	return sanitizeQuery(query, false)
+}
+
+func sanitizeQuery(query string, postgres bool) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '"' && postgres:
+			// Quoted identifier
+			j := skipQuoted(query, i, false)
+			b.WriteString(query[i:j])
+			i = j
+		case c == '\'' || c == '"':
+			i = skipQuoted(query, i, !postgres)
+			b.WriteByte('?')
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			// Positional placeholder
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		case c == '$' && dollarQuoteTag(query[i:]) != "":
+			tag := dollarQuoteTag(query[i:])
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+			b.WriteByte('?')
+		case isDigit(c) && (i == 0 || !isIdentifier(query[i-1])):
+			j := i
+			for j < len(query) && (isDigit(query[j]) || query[j] == '.') {
+				j++
+			}
+			b.WriteByte('?')
+			i = j
+		case (c == 'E' || c == 'e') && i+1 < len(query) && query[i+1] == '\'':
+			// Escape string constant, e.g. E'it\'s'
+			i = skipQuoted(query, i+1, true)
+			b.WriteByte('?')
+		case isIdentifier(c):
+			// Copy identifiers whole so that digits within them are kept
+			j := i
+			for j < len(query) && isIdentifier(query[j]) {
+				j++
+			}
+			b.WriteString(query[i:j])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// skipQuoted returns the index after the quoted string starting at query[start]. Quotes are
+// escaped by doubling them or, if backslashEscapes is set, with a backslash.
+func skipQuoted(query string, start int, backslashEscapes bool) int {
+	quote := query[start]
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			if backslashEscapes {
+				i++
+			}
+		case quote:
+			if i+1 < len(query) && query[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
+
+// dollarQuoteTag returns the opening delimiter of a dollar-quoted string at the start of query,
+// e.g. "$$" or "$body$", or "" if there is none.
+func dollarQuoteTag(query string) string {
+	for j := 1; j < len(query); j++ {
+		switch c := query[j]; {
+		case c == '$':
+			return query[:j+1]
+		case !isIdentifier(c) || (j == 1 && isDigit(c)):
+			return ""
+		}
+	}
+	return ""
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || c == '$' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func handleOtelErr(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
+
+// instrumentedConnector opens connections of the named driver and instruments them.
+func instrumentedConnector(driverName, dsn string, in *instrumentation) (driver.Connector, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return &otelConnector{Connector: connector, in: in}, nil
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type otelConnector struct {
+	driver.Connector
+	in *instrumentation
+}
+
+func (c *otelConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &otelConn{Conn: conn, in: c.in}, nil
+}
+
+// otelConn instruments a driver connection. Optional interfaces the wrapped connection does
+// not implement fall back to driver.ErrSkip or their default behaviour.
+type otelConn struct {
+	driver.Conn
+	in *instrumentation
+}
+
+// UnwrapConn returns the driver connection behind the instrumentation of a client, for use
+// within sql.Conn.Raw, whose callback otherwise receives the instrumented connection:
+//
+//	conn.Raw(func(driverConn interface{}) error {
+//		pgConn := db.UnwrapConn(driverConn).(*stdlib.Conn)
+//		...
+//	})
+//
+// Connections that are not instrumented are returned unchanged.
+func UnwrapConn(driverConn interface{}) interface{} {
+	if c, ok := driverConn.(interface{ Unwrap() driver.Conn }); ok {
+		return c.Unwrap()
+	}
+	return driverConn
+}
+
+// Unwrap returns the wrapped driver connection.
+func (c *otelConn) Unwrap() driver.Conn {
+	return c.Conn
+}
+
+func (c *otelConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *otelConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *otelConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *otelConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *otelConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = c.in.record(ctx, query, func() error {
+		result, err = execer.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+func (c *otelConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = c.in.record(ctx, query, func() error {
+		rows, err = queryer.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *otelConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &otelStmt{Stmt: stmt, query: query, in: c.in}, nil
+}
+
+func (c *otelConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *otelConn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	err = c.in.record(ctx, operationBegin, func() error {
+		if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+			tx, err = beginner.BeginTx(ctx, opts)
+			return err
+		}
+		tx, err = c.Conn.Begin()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &otelTx{Tx: tx, ctx: ctx, in: c.in}, nil
+}
+
+func (c *otelConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+type otelTx struct {
+	driver.Tx
+	ctx context.Context
+	in  *instrumentation
+}
+
+func (tx *otelTx) Commit() error {
+	return tx.in.record(tx.ctx, operationCommit, func() error {
+		return tx.Tx.Commit()
+	})
+}
+
+func (tx *otelTx) Rollback() error {
+	return tx.in.record(tx.ctx, operationRollback, func() error {
+		return tx.Tx.Rollback()
+	})
+}
+
+type otelStmt struct {
+	driver.Stmt
+	query string
+	in    *instrumentation
+}
+
+func (s *otelStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (s *otelStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	err = s.in.record(ctx, s.query, func() error {
+		if execer, ok := s.Stmt.(driver.StmtExecContext); ok {
+			result, err = execer.ExecContext(ctx, args)
+			return err
+		}
+		values, err := namedValues(args)
+		if err != nil {
+			return err
+		}
+		result, err = s.Stmt.Exec(values)
+		return err
+	})
+	return result, err
+}
+
+func (s *otelStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	err = s.in.record(ctx, s.query, func() error {
+		if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = queryer.QueryContext(ctx, args)
+			return err
+		}
+		values, err := namedValues(args)
+		if err != nil {
+			return err
+		}
+		rows, err = s.Stmt.Query(values)
+		return err
+	})
+	return rows, err
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("db: driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,105 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/raystack/salt/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInstrumentedClient(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	instrumented, err := db.New(db.Config{
+		Driver:       "postgres",
+		URL:          fmt.Sprintf(dsn, user, password, port, database),
+		MaxOpenConns: 5,
+	}, db.WithTracerProvider(tracerProvider), db.WithMeterProvider(meterProvider))
+	require.NoError(t, err)
+	defer instrumented.Close()
+
+	var name string
+	err = instrumented.GetContext(context.Background(), &name, "SELECT 'alice' WHERE 1 = $1", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", name)
+
+	var query sdktrace.ReadOnlySpan
+	for _, span := range exporter.GetSpans().Snapshots() {
+		if span.Name() == "SELECT" {
+			query = span
+		}
+	}
+	require.NotNil(t, query, "expected a span for the query")
+	attrs := attribute.NewSet(query.Attributes()...)
+	statement, _ := attrs.Value("db.query.text")
+	assert.Equal(t, "SELECT ? WHERE ? = $1", statement.AsString())
+	system, _ := attrs.Value("db.system")
+	assert.Equal(t, "postgresql", system.AsString())
+
+	exporter.Reset()
+	err = instrumented.GetContext(context.Background(), &name, `SELECT "n" FROM (SELECT $tag$it's$tag$ AS "n") AS "t1" WHERE E'\'' <> $1`, "x")
+	require.NoError(t, err)
+	spans := exporter.GetSpans().Snapshots()
+	require.NotEmpty(t, spans)
+	attrs = attribute.NewSet(spans[len(spans)-1].Attributes()...)
+	statement, _ = attrs.Value("db.query.text")
+	assert.Equal(t, `SELECT "n" FROM (SELECT ? AS "n") AS "t1" WHERE ? <> $1`, statement.AsString())
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	metrics := map[string]bool{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			metrics[m.Name] = true
+		}
+	}
+	for _, want := range []string{
+		"db.client.operation.duration",
+		"db.client.connection.count",
+		"db.client.connection.max",
+		"db.client.connection.wait_count",
+		"db.client.connection.wait_duration",
+	} {
+		assert.True(t, metrics[want], "expected metric %s", want)
+	}
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := map[string]string{
+		"SELECT * FROM users WHERE id = 42":                        "SELECT * FROM users WHERE id = ?",
+		"SELECT * FROM users WHERE name = 'O''Brien' AND id = $1":  "SELECT * FROM users WHERE name = ? AND id = $1",
+		"INSERT INTO t1 (a, b) VALUES (?, 3.14)":                   "INSERT INTO t1 (a, b) VALUES (?, ?)",
+		"UPDATE users SET name = 'x' WHERE id IN (1, 2)":           "UPDATE users SET name = ? WHERE id IN (?, ?)",
+		`SELECT * FROM users WHERE name = 'it\'s' AND id = ?`:      "SELECT * FROM users WHERE name = ? AND id = ?",
+		`SELECT * FROM users WHERE name = "it\"s" AND id = ?`:      "SELECT * FROM users WHERE name = ? AND id = ?",
+		`SELECT E'it\'s', e'\\' FROM t1`:                           "SELECT ?, ? FROM t1",
+		"SELECT $$it's$$, $body$a $$ b$body$ FROM t1 WHERE a = $1": "SELECT ?, ? FROM t1 WHERE a = $1",
+	}
+	for query, want := range tests {
+		assert.Equal(t, want, db.SanitizeQuery(query))
+	}
+}
+
+func TestUnwrapConn(t *testing.T) {
+	conn, err := client.Conn(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		assert.NotEqual(t, "*pq.conn", fmt.Sprintf("%T", driverConn))
+		assert.Equal(t, "*pq.conn", fmt.Sprintf("%T", db.UnwrapConn(driverConn)))
+		return nil
+	})
+	require.NoError(t, err)
+}
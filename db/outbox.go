@@ -0,0 +1,386 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+)
+
+//go:embed outbox
+var outboxMigrations embed.FS
+
+const (
+	outboxMigrationsTable = "outbox_schema_migrations"
+
+	defaultRelayInterval     = time.Second
+	defaultRelayBatchSize    = 100
+	defaultRelayMaxAttempts  = 10
+	defaultRelayLeaseTimeout = 30 * time.Second
+	defaultRelayBackoff      = time.Second
+	defaultRelayMaxBackoff   = 5 * time.Minute
+)
+
+// NewOutboxMigrator returns a Migrator for the outbox_events table of the transactional outbox,
+// for the driver of the config. Its migrations are recorded in the outbox_schema_migrations
+// table, apart from the migrations of the application, so run it alongside them:
+//
+//	if err := db.NewOutboxMigrator(cfg).Up(); err != nil {
+//		return err
+//	}
+func NewOutboxMigrator(cfg Config, opts ...MigratorOption) *Migrator {
+	dir := "postgres"
+	if cfg.Driver == "mysql" {
+		dir = "mysql"
+	}
+	opts = append([]MigratorOption{WithMigrationsTable(outboxMigrationsTable)}, opts...)
+	return NewMigrator(cfg, outboxMigrations, path.Join("outbox", dir), opts...)
+}
+
+// OutboxEvent is an event to publish through the outbox.
+type OutboxEvent struct {
+	Topic string
+	// Key identifies the entity of the event, e.g. for partitioning. Optional.
+	Key     string
+	Payload []byte
+	Headers map[string]string
+}
+
+// OutboxMessage is an event of the outbox handed to a Publisher.
+type OutboxMessage struct {
+	OutboxEvent
+	ID int64
+	// Attempts counts the previous attempts to publish the message.
+	Attempts  int
+	CreatedAt time.Time
+}
+
+// Publisher publishes outbox messages, e.g. to a message broker. Messages are published at least
+// once: a message is published again if recording its publication fails, so consumers should
+// deduplicate by message ID.
+type Publisher interface {
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+// Enqueue adds events to the outbox within the transaction started by RunInTx that the context
+// belongs to, so that they are published by an OutboxRelay if and only if the transaction
+// commits along with the domain rows they describe. It fails outside of such a transaction;
+// use EnqueueTx within WithTxn.
+//
+// Example:
+//
+//	err := client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+//		if _, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES ($1)", name); err != nil {
+//			return err
+//		}
+//		return client.Enqueue(ctx, db.OutboxEvent{Topic: "users.created", Payload: payload})
+//	})
+func (c *Client) Enqueue(ctx context.Context, events ...OutboxEvent) error {
+	tx, ok := TxFromContext(ctx)
+	if !ok {
+		return errors.New("outbox: enqueue outside of a transaction")
+	}
+	return enqueue(ctx, tx, events)
+}
+
+// EnqueueTx adds events to the outbox within tx, like Enqueue, e.g. within WithTxn:
+//
+//	err := client.WithTxn(ctx, sql.TxOptions{}, func(tx *sqlx.Tx) error {
+//		if _, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES ($1)", name); err != nil {
+//			return err
+//		}
+//		return client.EnqueueTx(ctx, tx, db.OutboxEvent{Topic: "users.created", Payload: payload})
+//	})
+func (c *Client) EnqueueTx(ctx context.Context, tx *sqlx.Tx, events ...OutboxEvent) error {
+	if tx == nil {
+		return errors.New("outbox: enqueue outside of a transaction")
+	}
+	return enqueue(ctx, tx, events)
+}
+
+func enqueue(ctx context.Context, tx *sqlx.Tx, events []OutboxEvent) error {
+	now := time.Now().UTC()
+	query := tx.Rebind(`INSERT INTO outbox_events (topic, event_key, payload, headers, attempts, last_error, available_at, created_at)
+		VALUES (?, ?, ?, ?, 0, '', ?, ?)`)
+	for _, event := range events {
+		if event.Topic == "" {
+			return errors.New("outbox: event topic is empty")
+		}
+		headers, err := json.Marshal(event.Headers)
+		if err != nil {
+			return fmt.Errorf("outbox: encode headers: %w", err)
+		}
+		if event.Headers == nil {
+			headers = []byte("{}")
+		}
+		if event.Payload == nil {
+			event.Payload = []byte{}
+		}
+		if _, err := tx.ExecContext(ctx, query, event.Topic, event.Key, event.Payload, string(headers), now, now); err != nil {
+			return fmt.Errorf("outbox: enqueue %s: %w", event.Topic, err)
+		}
+	}
+	return nil
+}
+
+// RelayOption configures an OutboxRelay.
+type RelayOption func(*OutboxRelay)
+
+// WithRelayInterval sets how often the outbox is polled when it has no more messages to
+// publish. Defaults to 1s.
+func WithRelayInterval(interval time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.interval = interval
+	}
+}
+
+// WithRelayBatchSize sets how many messages are leased and published at a time. Defaults to 100.
+func WithRelayBatchSize(size int) RelayOption {
+	return func(r *OutboxRelay) {
+		r.batchSize = size
+	}
+}
+
+// WithMaxAttempts sets how many times publishing a message is attempted before giving up on
+// it. Messages given up on stay in the outbox with their last error. Defaults to 10.
+func WithMaxAttempts(attempts int) RelayOption {
+	return func(r *OutboxRelay) {
+		r.maxAttempts = attempts
+	}
+}
+
+// WithRetryBackoff sets the delay before retrying a message that failed to publish. It starts
+// at initial and doubles after every attempt, up to max, with jitter. Defaults to 1s and 5m.
+func WithRetryBackoff(initial, max time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.backoff = initial
+		r.maxBackoff = max
+	}
+}
+
+// WithLeaseTimeout sets how long a batch of messages stays leased by a relay. Messages a relay
+// fails to publish in time, e.g. as it crashed, are released to other relays. Defaults to 30s.
+func WithLeaseTimeout(timeout time.Duration) RelayOption {
+	return func(r *OutboxRelay) {
+		r.leaseTimeout = timeout
+	}
+}
+
+// WithRelayErrorHandler sets the handler of the errors of background relaying. Defaults to
+// otel.Handle.
+func WithRelayErrorHandler(handler func(error)) RelayOption {
+	return func(r *OutboxRelay) {
+		r.errorHandler = handler
+	}
+}
+
+// OutboxRelay publishes the messages of the outbox in the order they were enqueued, retrying
+// failed messages with backoff. Messages are leased for the lease timeout in a short
+// transaction using SELECT ... FOR UPDATE SKIP LOCKED, so that any number of relays, e.g. one
+// per replica of a service, share the outbox without publishing a message twice while its
+// lease holds. Publishing happens outside of any transaction, and the outcome of every message
+// is recorded as soon as it is known. Retried messages may be published after later messages.
+type OutboxRelay struct {
+	client       *Client
+	publisher    Publisher
+	interval     time.Duration
+	batchSize    int
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	leaseTimeout time.Duration
+	errorHandler func(error)
+}
+
+// NewOutboxRelay creates an OutboxRelay publishing the outbox of the client to publisher.
+func (c *Client) NewOutboxRelay(publisher Publisher, opts ...RelayOption) *OutboxRelay {
+	r := &OutboxRelay{
+		client:       c,
+		publisher:    publisher,
+		interval:     defaultRelayInterval,
+		batchSize:    defaultRelayBatchSize,
+		maxAttempts:  defaultRelayMaxAttempts,
+		backoff:      defaultRelayBackoff,
+		maxBackoff:   defaultRelayMaxBackoff,
+		leaseTimeout: defaultRelayLeaseTimeout,
+		errorHandler: otel.Handle,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run relays messages until the context is done. Errors are passed to the error handler and
+// relaying continues at the next interval.
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.errorHandler(err)
+		}
+		if err == nil && n == r.batchSize {
+			// More messages are likely waiting
+			timer.Reset(0)
+		} else {
+			timer.Reset(r.interval)
+		}
+	}
+}
+
+// RelayOnce leases a batch of messages that are due, publishes them and records the outcome. It
+// returns the number of messages leased.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.leaseTimeout)
+	defer cancel()
+
+	// Leases are recorded to the microsecond, the precision of the outbox_events columns, as
+	// they identify the lease when recording outcomes
+	leasedUntil := time.Now().UTC().Add(r.leaseTimeout).Truncate(time.Microsecond)
+	var messages []OutboxMessage
+	err := r.client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		var err error
+		messages, err = r.lease(ctx, tx, leasedUntil)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	published := r.client.Rebind(`UPDATE outbox_events SET last_error = '', published_at = ?, leased_until = NULL
+		WHERE id = ? AND leased_until = ?`)
+	failed := r.client.Rebind(`UPDATE outbox_events SET last_error = ?, available_at = ?, leased_until = NULL
+		WHERE id = ? AND leased_until = ?`)
+	for _, msg := range messages {
+		err := r.publisher.Publish(ctx, msg)
+		now := time.Now().UTC()
+		if err != nil {
+			if ctx.Err() != nil {
+				// The lease of the remaining messages expires, after which they are retried
+				return len(messages), fmt.Errorf("outbox: publish message %d: %w", msg.ID, err)
+			}
+			// The attempt was counted when leasing the message
+			_, err = r.client.ExecContext(ctx, failed, err.Error(), now.Add(r.retryDelay(msg.Attempts)), msg.ID, leasedUntil)
+			if err != nil {
+				return len(messages), fmt.Errorf("outbox: record failure of message %d: %w", msg.ID, err)
+			}
+			continue
+		}
+		if _, err := r.client.ExecContext(ctx, published, now, msg.ID, leasedUntil); err != nil {
+			return len(messages), fmt.Errorf("outbox: record publication of message %d: %w", msg.ID, err)
+		}
+	}
+	return len(messages), nil
+}
+
+// lease selects the messages that are due and not leased by another relay, and leases them
+// until leasedUntil, counting an attempt to publish them.
+func (r *OutboxRelay) lease(ctx context.Context, tx *sqlx.Tx, leasedUntil time.Time) ([]OutboxMessage, error) {
+	now := time.Now().UTC()
+	query := tx.Rebind(`SELECT id, topic, event_key, payload, headers, attempts, created_at FROM outbox_events
+		WHERE published_at IS NULL AND attempts < ? AND available_at <= ? AND (leased_until IS NULL OR leased_until <= ?)
+		ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED`)
+	rows, err := tx.QueryxContext(ctx, query, r.maxAttempts, now, now, r.batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: lease messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	var ids []int64
+	for rows.Next() {
+		var msg OutboxMessage
+		var headers []byte
+		if err := rows.Scan(&msg.ID, &msg.Topic, &msg.Key, &msg.Payload, &headers, &msg.Attempts, &msg.CreatedAt); err != nil {
+			return nil, fmt.Errorf("outbox: lease messages: %w", err)
+		}
+		if err := json.Unmarshal(headers, &msg.Headers); err != nil {
+			return nil, fmt.Errorf("outbox: decode headers of message %d: %w", msg.ID, err)
+		}
+		messages = append(messages, msg)
+		ids = append(ids, msg.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("outbox: lease messages: %w", err)
+	}
+	// The connection is busy until the rows are closed
+	if err := rows.Close(); err != nil {
+		return nil, fmt.Errorf("outbox: lease messages: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	update, args, err := sqlx.In(`UPDATE outbox_events SET attempts = attempts + 1, leased_until = ? WHERE id IN (?)`, leasedUntil, ids)
+	if err != nil {
+		return nil, fmt.Errorf("outbox: lease messages: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind(update), args...); err != nil {
+		return nil, fmt.Errorf("outbox: lease messages: %w", err)
+	}
+	return messages, nil
+}
+
+// retryDelay returns the jittered delay before retrying a message after the given number of
+// previous attempts.
+func (r *OutboxRelay) retryDelay(attempts int) time.Duration {
+	delay := r.backoff
+	for i := 0; i < attempts && delay < r.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, r.maxBackoff)
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// MemoryPublisher is a Publisher recording messages in memory, for tests.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []OutboxMessage
+	err      error
+}
+
+// NewMemoryPublisher creates an empty MemoryPublisher.
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish records the message, or returns the error set with SetError.
+func (p *MemoryPublisher) Publish(_ context.Context, msg OutboxMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// SetError makes Publish fail with err, or succeed again when err is nil.
+func (p *MemoryPublisher) SetError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Messages returns the published messages, in order.
+func (p *MemoryPublisher) Messages() []OutboxMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]OutboxMessage(nil), p.messages...)
+}
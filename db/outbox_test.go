@@ -0,0 +1,158 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/salt/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	migrator := db.NewOutboxMigrator(db.Config{Driver: "postgres", URL: client.ConnectionURL()})
+	require.NoError(t, migrator.Up())
+	defer migrator.Down(1)
+
+	err := client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		return client.Enqueue(ctx,
+			db.OutboxEvent{Topic: "users.created", Key: "1", Payload: []byte(`{"id":1}`), Headers: map[string]string{"source": "test"}},
+			db.OutboxEvent{Topic: "users.created", Key: "2", Payload: []byte(`{"id":2}`)},
+		)
+	})
+	require.NoError(t, err)
+
+	err = client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+		if err := client.Enqueue(ctx, db.OutboxEvent{Topic: "users.deleted", Payload: []byte(`{}`)}); err != nil {
+			return err
+		}
+		return errors.New("domain write failed")
+	})
+	require.Error(t, err)
+
+	err = client.WithTxn(ctx, sql.TxOptions{}, func(tx *sqlx.Tx) error {
+		if err := client.EnqueueTx(ctx, tx, db.OutboxEvent{Topic: "users.suspended", Payload: []byte(`{}`)}); err != nil {
+			return err
+		}
+		return errors.New("domain write failed")
+	})
+	require.Error(t, err)
+
+	// The context does not carry the transaction of WithTxn, so Enqueue must not write on its own
+	err = client.WithTxn(ctx, sql.TxOptions{}, func(tx *sqlx.Tx) error {
+		return client.Enqueue(ctx, db.OutboxEvent{Topic: "users.suspended", Payload: []byte(`{}`)})
+	})
+	require.Error(t, err)
+
+	publisher := db.NewMemoryPublisher()
+	relay := client.NewOutboxRelay(publisher, db.WithRetryBackoff(time.Hour, time.Hour))
+
+	t.Run("relays committed events only", func(t *testing.T) {
+		n, err := relay.RelayOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		messages := publisher.Messages()
+		require.Len(t, messages, 2)
+		assert.Equal(t, "1", messages[0].Key)
+		assert.Equal(t, []byte(`{"id":1}`), messages[0].Payload)
+		assert.Equal(t, map[string]string{"source": "test"}, messages[0].Headers)
+		assert.Equal(t, "2", messages[1].Key)
+
+		n, err = relay.RelayOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("reschedules failed messages", func(t *testing.T) {
+		err := client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			return client.Enqueue(ctx, db.OutboxEvent{Topic: "users.updated"})
+		})
+		require.NoError(t, err)
+
+		publisher.SetError(errors.New("broker unavailable"))
+		n, err := relay.RelayOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		var attempts int
+		var lastError string
+		err = client.QueryRowxContext(ctx, `SELECT attempts, last_error FROM outbox_events WHERE topic = 'users.updated'`).Scan(&attempts, &lastError)
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Equal(t, "broker unavailable", lastError)
+
+		// The retry is not due before the backoff
+		publisher.SetError(nil)
+		n, err = relay.RelayOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	})
+
+	t.Run("skips messages leased by another relay", func(t *testing.T) {
+		err := client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			return client.Enqueue(ctx, db.OutboxEvent{Topic: "users.renamed"})
+		})
+		require.NoError(t, err)
+
+		blocking := make(chan struct{})
+		leased := make(chan struct{})
+		done := make(chan error)
+		slow := client.NewOutboxRelay(publisherFunc(func(ctx context.Context, msg db.OutboxMessage) error {
+			close(leased)
+			<-blocking
+			return nil
+		}))
+		go func() {
+			_, err := slow.RelayOnce(ctx)
+			done <- err
+		}()
+
+		<-leased
+		n, err := relay.RelayOnce(ctx)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		// No transaction holds the row locked while the message is published
+		var id int64
+		err = client.RunInTx(ctx, func(ctx context.Context, tx *sqlx.Tx) error {
+			return tx.GetContext(ctx, &id, `SELECT id FROM outbox_events WHERE topic = 'users.renamed' FOR UPDATE NOWAIT`)
+		})
+		assert.NoError(t, err)
+
+		close(blocking)
+		require.NoError(t, <-done)
+		var published bool
+		err = client.GetContext(ctx, &published, `SELECT published_at IS NOT NULL FROM outbox_events WHERE id = $1`, id)
+		require.NoError(t, err)
+		assert.True(t, published)
+	})
+
+	t.Run("releases expired leases", func(t *testing.T) {
+		err := client.WithTxn(ctx, sql.TxOptions{}, func(tx *sqlx.Tx) error {
+			return client.EnqueueTx(ctx, tx, db.OutboxEvent{Topic: "users.archived"})
+		})
+		require.NoError(t, err)
+		_, err = client.ExecContext(ctx, `UPDATE outbox_events SET attempts = 1, leased_until = now() - interval '1 second'
+			WHERE topic = 'users.archived'`)
+		require.NoError(t, err)
+
+		n, err := relay.RelayOnce(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		messages := publisher.Messages()
+		assert.Equal(t, "users.archived", messages[len(messages)-1].Topic)
+		assert.Equal(t, 1, messages[len(messages)-1].Attempts)
+	})
+}
+
+type publisherFunc func(ctx context.Context, msg db.OutboxMessage) error
+
+func (f publisherFunc) Publish(ctx context.Context, msg db.OutboxMessage) error {
+	return f(ctx, msg)
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/raystack/salt/data/rql"
+)
+
+const defaultPageSize = 50
+
+// Page is a page of results of a keyset paginated query.
+type Page[T any] struct {
+	Items []T
+	// NextCursor selects the next page, empty on the last page.
+	NextCursor string
+	// PrevCursor selects the previous page, empty on the first page.
+	PrevCursor string
+}
+
+// PageOption configures SelectPage.
+type PageOption func(*pageOptions)
+
+type pageOptions struct {
+	tiebreaker string
+	size       int
+	buildOpts  []rql.BuildOption
+	conditions []string
+	args       []interface{}
+}
+
+// WithTiebreaker sets the key appended to the sort keys, unless already present, so that they
+// uniquely identify rows. It must be a unique, non-null key. Defaults to "id".
+func WithTiebreaker(key string) PageOption {
+	return func(o *pageOptions) {
+		o.tiebreaker = key
+	}
+}
+
+// WithPageSize sets the page size used when the query has no limit. Defaults to 50.
+func WithPageSize(size int) PageOption {
+	return func(o *pageOptions) {
+		o.size = size
+	}
+}
+
+// WithWhere adds a base condition of the query, e.g. `"tenant_id" = $1`, to the WHERE clause
+// built from the filters of the rql query, joined with AND. Base conditions cannot be part of
+// the query passed to SelectPage, as the built clauses are appended to it. Placeholders of
+// conditions are bound to args in order; Postgres placeholders are numbered from $1 across
+// all conditions, and those of the built clauses follow them.
+func WithWhere(condition string, args ...interface{}) PageOption {
+	return func(o *pageOptions) {
+		o.conditions = append(o.conditions, condition)
+		o.args = append(o.args, args...)
+	}
+}
+
+// WithBuildOptions sets options of the SQL builder, e.g. rql.WithSearchMode. The dialect is
+// derived from the driver of the client.
+func WithBuildOptions(opts ...rql.BuildOption) PageOption {
+	return func(o *pageOptions) {
+		o.buildOpts = append(o.buildOpts, opts...)
+	}
+}
+
+// SelectPage runs a keyset paginated query on the Reader for the context: it appends the
+// filters, search and sort of q to query, seeks to the position of cursor and returns the next
+// q.Limit rows. Unlike offsets, seeking stays fast on large tables as long as an index covers
+// the sort keys.
+//
+// query must end before its WHERE clause, e.g. "SELECT * FROM organizations", since the WHERE
+// clause is built from q; pass base conditions with WithWhere instead.
+//
+// T is scanned with sqlx and must be tagged for rql as well, see rql.BuildSQL. An empty cursor
+// selects the first page, and the cursors of the returned page select its neighbours; cursors
+// are opaque to clients and only valid for the same sort keys. When rows were deleted meanwhile,
+// a previous page that turns out empty is replaced by the first page, and an empty next page
+// keeps a cursor back to the rows before it.
+//
+// Example:
+//
+//	page, err := db.SelectPage[Organization](ctx, client, "SELECT * FROM organizations", query, req.Cursor,
+//		db.WithWhere(`"owner_id" = $1`, ownerID))
+func SelectPage[T any](ctx context.Context, c *Client, query string, q *rql.Query, cursor string, opts ...PageOption) (*Page[T], error) {
+	o := pageOptions{tiebreaker: "id", size: defaultPageSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if q.Offset > 0 {
+		return nil, errors.New("offset cannot be combined with keyset pagination")
+	}
+
+	paged := *q
+	if !slices.ContainsFunc(paged.Sort, func(s rql.Sort) bool { return strings.EqualFold(s.Name, o.tiebreaker) }) {
+		paged.Sort = append(slices.Clone(paged.Sort), rql.Sort{Name: o.tiebreaker, Order: rql.SORT_ORDER_ASC})
+	}
+	size := paged.Limit
+	if size == 0 {
+		size = o.size
+	}
+	// One more row tells whether there is a page after this one
+	paged.Limit = size + 1
+
+	var checkStruct T
+	var seek *rql.Cursor
+	if cursor != "" {
+		var err error
+		if seek, err = rql.DecodeCursor(cursor, &paged, checkStruct); err != nil {
+			return nil, err
+		}
+	}
+
+	buildOpts := append([]rql.BuildOption{rql.WithDialect(c.dialect()), rql.WithCursor(seek), rql.WithArgOffset(len(o.args))}, o.buildOpts...)
+	clauses, err := rql.BuildSQL(&paged, checkStruct, buildOpts...)
+	if err != nil {
+		return nil, err
+	}
+	args := clauses.Args
+	if len(o.conditions) > 0 {
+		where := "WHERE (" + strings.Join(o.conditions, ") AND (") + ")"
+		if clauses.Where != "" {
+			where += " AND " + strings.TrimPrefix(clauses.Where, "WHERE ")
+		}
+		clauses.Where = where
+		args = append(slices.Clone(o.args), clauses.Args...)
+	}
+	var items []T
+	if err := c.Reader(ctx).SelectContext(ctx, &items, query+" "+clauses.String(), args...); err != nil {
+		return nil, err
+	}
+
+	backward := seek != nil && seek.Backward
+	more := len(items) > size
+	if more {
+		items = items[:size]
+	}
+	if backward {
+		// Backward pages are selected in reverse order
+		slices.Reverse(items)
+	}
+
+	if backward && len(items) == 0 {
+		// No rows are left before the cursor, e.g. as they were deleted, so the first page follows
+		return SelectPage[T](ctx, c, query, q, "", opts...)
+	}
+
+	page := &Page[T]{Items: items}
+	if len(items) == 0 {
+		if seek != nil {
+			// No rows are left after the cursor, but the client can still turn back
+			prev := rql.Cursor{Values: seek.Values, Backward: true}
+			if page.PrevCursor, err = prev.Encode(&paged); err != nil {
+				return nil, fmt.Errorf("previous cursor: %w", err)
+			}
+		}
+		return page, nil
+	}
+	if more || backward {
+		if page.NextCursor, err = rql.EncodeCursor(&paged, items[len(items)-1], false); err != nil {
+			return nil, fmt.Errorf("next cursor: %w", err)
+		}
+	}
+	if (more && backward) || (seek != nil && !backward) {
+		if page.PrevCursor, err = rql.EncodeCursor(&paged, items[0], true); err != nil {
+			return nil, fmt.Errorf("previous cursor: %w", err)
+		}
+	}
+	return page, nil
+}
+
+// dialect returns the SQL dialect of the driver of the client.
+func (c *Client) dialect() rql.Dialect {
+	if c.cfg.Driver == "mysql" {
+		return rql.MySQL
+	}
+	return rql.Postgres
+}
@@ -0,0 +1,108 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/raystack/salt/data/rql"
+	"github.com/raystack/salt/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pageItem struct {
+	ID    int    `db:"id" rql:"name=id,type=number"`
+	Title string `db:"title" rql:"name=title,type=string"`
+}
+
+func TestSelectPage(t *testing.T) {
+	ctx := context.Background()
+	_, err := client.ExecContext(ctx, `CREATE TABLE page_items (id INT PRIMARY KEY, title TEXT NOT NULL)`)
+	require.NoError(t, err)
+	defer client.ExecContext(ctx, `DROP TABLE page_items`)
+	// Titles repeat, so the id tiebreaker decides the order within a title
+	for i := 1; i <= 7; i++ {
+		_, err := client.ExecContext(ctx, `INSERT INTO page_items (id, title) VALUES ($1, $2)`, i, fmt.Sprintf("title-%d", i/2))
+		require.NoError(t, err)
+	}
+
+	query := &rql.Query{Sort: []rql.Sort{{Name: "title", Order: "desc"}}, Limit: 3}
+	ids := func(page *db.Page[pageItem]) []int {
+		var ids []int
+		for _, item := range page.Items {
+			ids = append(ids, item.ID)
+		}
+		return ids
+	}
+
+	first, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, "")
+	require.NoError(t, err)
+	assert.Equal(t, []int{6, 7, 4}, ids(first))
+	assert.Empty(t, first.PrevCursor)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, first.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 2, 3}, ids(second))
+
+	last, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, second.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, ids(last))
+	assert.Empty(t, last.NextCursor)
+
+	back, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, last.PrevCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 2, 3}, ids(back))
+	assert.Equal(t, second.NextCursor, back.NextCursor)
+
+	front, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, back.PrevCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []int{6, 7, 4}, ids(front))
+	assert.Empty(t, front.PrevCursor)
+
+	_, err = db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items",
+		&rql.Query{Sort: []rql.Sort{{Name: "title", Order: "asc"}}}, first.NextCursor)
+	assert.ErrorIs(t, err, rql.ErrInvalidCursor)
+
+	t.Run("base conditions", func(t *testing.T) {
+		filtered := &rql.Query{
+			Filters: []rql.Filter{{Name: "title", Operator: "neq", Value: "title-3"}},
+			Sort:    []rql.Sort{{Name: "title", Order: "desc"}},
+			Limit:   3,
+		}
+		page, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", filtered, "",
+			db.WithWhere("id > $1", 1), db.WithWhere("id < $2", 7))
+		require.NoError(t, err)
+		assert.Equal(t, []int{4, 5, 2}, ids(page))
+
+		next, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", filtered, page.NextCursor,
+			db.WithWhere("id > $1", 1), db.WithWhere("id < $2", 7))
+		require.NoError(t, err)
+		assert.Equal(t, []int{3}, ids(next))
+	})
+
+	t.Run("empty pages keep cursors", func(t *testing.T) {
+		_, err := client.ExecContext(ctx, `DELETE FROM page_items WHERE id IN (6, 7, 4)`)
+		require.NoError(t, err)
+
+		// The rows before the second page are gone, so going back leads to the first page
+		back, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, second.PrevCursor)
+		require.NoError(t, err)
+		assert.Equal(t, []int{5, 2, 3}, ids(back))
+		assert.Empty(t, back.PrevCursor)
+		assert.NotEmpty(t, back.NextCursor)
+
+		_, err = client.ExecContext(ctx, `DELETE FROM page_items WHERE id = 1`)
+		require.NoError(t, err)
+		empty, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, second.NextCursor)
+		require.NoError(t, err)
+		assert.Empty(t, empty.Items)
+		assert.Empty(t, empty.NextCursor)
+		require.NotEmpty(t, empty.PrevCursor)
+
+		prev, err := db.SelectPage[pageItem](ctx, client, "SELECT id, title FROM page_items", query, empty.PrevCursor)
+		require.NoError(t, err)
+		assert.Equal(t, []int{5, 2}, ids(prev))
+	})
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const defaultReplicaHealthInterval = 5 * time.Second
+
+// WithReplicaHealthCheck configures the health checks of read replicas. Replicas failing
+// their checks are ejected from read routing until they recover. By default, replicas are
+// pinged every 5s.
+func WithReplicaHealthCheck(opts ...HealthOption) Option {
+	return func(o *options) {
+		o.replicaHealth = append(o.replicaHealth, opts...)
+	}
+}
+
+type replica struct {
+	db          *sqlx.DB
+	health      *HealthChecker
+	poolMetrics metric.Registration
+}
+
+// replicaSet routes reads to healthy replicas in round-robin order.
+type replicaSet struct {
+	nodes []*replica
+	next  atomic.Uint64
+}
+
+// openReplicas opens a pool for every replica URL of the config. Replicas are not required to
+// be reachable: unreachable replicas stay ejected until their health checks succeed.
+func openReplicas(cfg Config, o options) (*replicaSet, error) {
+	if len(cfg.ReplicaURLs) == 0 {
+		return nil, nil
+	}
+	set := &replicaSet{}
+	healthOpts := append([]HealthOption{WithHealthInterval(defaultReplicaHealthInterval)}, o.replicaHealth...)
+	for _, dsn := range cfg.ReplicaURLs {
+		db, poolMetrics, err := open(cfg, dsn, o)
+		if err != nil {
+			set.close()
+			return nil, err
+		}
+		node := &replica{db: db, health: NewHealthChecker(db, healthOpts...), poolMetrics: poolMetrics}
+		node.health.Start()
+		set.nodes = append(set.nodes, node)
+	}
+	return set, nil
+}
+
+// pick returns the next healthy replica, nil if there is none.
+func (s *replicaSet) pick() *sqlx.DB {
+	n := uint64(len(s.nodes))
+	start := s.next.Add(1) - 1
+	for i := uint64(0); i < n; i++ {
+		if node := s.nodes[(start+i)%n]; node.health.Healthy() {
+			return node.db
+		}
+	}
+	return nil
+}
+
+func (s *replicaSet) close() {
+	for _, node := range s.nodes {
+		node.health.Stop()
+		if err := node.poolMetrics.Unregister(); err != nil {
+			otel.Handle(err)
+		}
+		node.db.Close()
+	}
+}
+
+type primaryKey struct{}
+
+// WithPrimary returns a context whose reads go to the primary, e.g. to read your own writes
+// right after making them, before replicas catch up.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Reader returns where reads for the context should go: the transaction the context belongs
+// to, the primary if the context was created by WithPrimary, or else the next healthy read
+// replica in round-robin order. Without healthy replicas, reads go to the primary.
+func (c *Client) Reader(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	if primary, _ := ctx.Value(primaryKey{}).(bool); primary || c.replicas == nil {
+		return c.DB
+	}
+	if db := c.replicas.pick(); db != nil {
+		return db
+	}
+	return c.DB
+}
+
+// WithReadTimeout runs a read operation with the query timeout, like WithTimeout, passing it
+// the Reader for the context.
+func (c *Client) WithReadTimeout(ctx context.Context, op func(ctx context.Context, reader Querier) error) error {
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, c.queryTimeOut)
+	defer cancel()
+
+	return op(ctxWithTimeout, c.Reader(ctxWithTimeout))
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SQLSTATE codes of transient Postgres failures after which a transaction can be retried.
+const (
+	serializationFailure = "40001"
+	deadlockDetected     = "40P01"
+)
+
+const (
+	defaultTxRetries    = 3
+	defaultTxBackoff    = 50 * time.Millisecond
+	defaultTxMaxBackoff = time.Second
+)
+
+// Querier is implemented by both *sqlx.DB and *sqlx.Tx, so that repositories can run their
+// queries the same way inside and outside of a transaction.
+type Querier interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+}
+
+// TxOption configures a transaction run by RunInTx.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	sql.TxOptions
+	retries    int
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+// ReadCommitted runs the transaction at the read committed isolation level.
+func ReadCommitted() TxOption {
+	return WithIsolation(sql.LevelReadCommitted)
+}
+
+// RepeatableRead runs the transaction at the repeatable read isolation level.
+func RepeatableRead() TxOption {
+	return WithIsolation(sql.LevelRepeatableRead)
+}
+
+// Serializable runs the transaction at the serializable isolation level. Serialization
+// failures are retried, see WithRetries.
+func Serializable() TxOption {
+	return WithIsolation(sql.LevelSerializable)
+}
+
+// ReadOnly runs the transaction in read-only mode.
+func ReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithIsolation runs the transaction at the given isolation level.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.Isolation = level
+	}
+}
+
+// WithRetries sets how many times a transaction is retried after a serialization failure or
+// deadlock (SQLSTATE 40001 or 40P01). Defaults to 3; 0 disables retries.
+func WithRetries(retries int) TxOption {
+	return func(o *txOptions) {
+		o.retries = retries
+	}
+}
+
+// WithBackoff sets the delay before the first retry and the maximum delay between retries.
+// The delay doubles after every attempt and is jittered. Defaults to 50ms and 1s.
+func WithBackoff(initial, max time.Duration) TxOption {
+	return func(o *txOptions) {
+		o.backoff = initial
+		o.maxBackoff = max
+	}
+}
+
+type txKey struct{}
+
+// txState is the transaction carried in a context and its savepoint nesting depth.
+type txState struct {
+	tx    *sqlx.Tx
+	depth int
+}
+
+// TxFromContext returns the transaction started by RunInTx that the context belongs to.
+func TxFromContext(ctx context.Context) (*sqlx.Tx, bool) {
+	state, ok := ctx.Value(txKey{}).(*txState)
+	if !ok {
+		return nil, false
+	}
+	return state.tx, true
+}
+
+// Querier returns the transaction the context belongs to, or the database otherwise.
+// Contexts belong to the transactions of RunInTx only: the callback of WithTxn is not passed
+// a context, so code it calls with the outer context runs outside of its transaction.
+// Repositories use it to join the transaction of their caller:
+//
+//	func (r *Repository) Create(ctx context.Context, u User) error {
+//		_, err := r.client.Querier(ctx).ExecContext(ctx, "INSERT INTO users (id, name) VALUES ($1, $2)", u.ID, u.Name)
+//		return err
+//	}
+func (c *Client) Querier(ctx context.Context) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return c.DB
+}
+
+// RunInTx runs fn in a transaction, which is committed if fn returns nil and rolled back
+// otherwise. The context passed to fn carries the transaction (see TxFromContext and Querier).
+//
+// Transactions failing with a serialization failure or a deadlock are retried with backoff.
+// fn must therefore be safe to run several times.
+//
+// When ctx already belongs to a transaction, fn joins it within a savepoint instead: an
+// error rolls back to the savepoint only, and options and retries are left to the outermost
+// transaction.
+func (c *Client) RunInTx(ctx context.Context, fn func(ctx context.Context, tx *sqlx.Tx) error, opts ...TxOption) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return runInSavepoint(ctx, state, fn)
+	}
+
+	o := txOptions{retries: defaultTxRetries, backoff: defaultTxBackoff, maxBackoff: defaultTxMaxBackoff}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	backoff := o.backoff
+	for attempt := 0; ; attempt++ {
+		err := c.runTx(ctx, o.TxOptions, fn)
+		if err == nil || attempt >= o.retries || !IsRetryable(err) {
+			return err
+		}
+
+		// Sleep for a random duration between half and all of the backoff
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (giving up retrying: %s)", err, ctx.Err())
+		case <-time.After(delay):
+		}
+		backoff = min(2*backoff, o.maxBackoff)
+	}
+}
+
+func (c *Client) runTx(ctx context.Context, opts sql.TxOptions, fn func(ctx context.Context, tx *sqlx.Tx) error) (err error) {
+	tx, err := c.BeginTxx(ctx, &opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, &txState{tx: tx}), tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("rollback error: %s while executing: %w", rbErr, err)
+		}
+		return fmt.Errorf("rollback: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+func runInSavepoint(ctx context.Context, state *txState, fn func(ctx context.Context, tx *sqlx.Tx) error) (err error) {
+	nested := &txState{tx: state.tx, depth: state.depth + 1}
+	savepoint := fmt.Sprintf("sp_%d", nested.depth)
+	if _, err := state.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint)
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, nested), state.tx); err != nil {
+		if _, rbErr := state.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+savepoint); rbErr != nil {
+			return fmt.Errorf("rollback to savepoint error: %s while executing: %w", rbErr, err)
+		}
+		return fmt.Errorf("rollback to savepoint: %w", err)
+	}
+	if _, err := state.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+savepoint); err != nil {
+		return fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil
+}
+
+// IsRetryable reports whether err is a Postgres serialization failure or deadlock
+// (SQLSTATE 40001 or 40P01), after which the transaction can be retried.
+func IsRetryable(err error) bool {
+	var code string
+	var pqErr *pq.Error
+	var stateErr interface{ SQLState() string }
+	switch {
+	case errors.As(err, &pqErr):
+		code = string(pqErr.Code)
+	case errors.As(err, &stateErr):
+		code = stateErr.SQLState()
+	default:
+		return false
+	}
+	return code == serializationFailure || code == deadlockDetected
+}
@@ -0,0 +1,173 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/raystack/salt/db"
+	"github.com/stretchr/testify/assert"
+)
+
+func resetUsersTable(t *testing.T) {
+	t.Helper()
+	if _, err := client.Exec(dropTableQuery); err != nil {
+		log.Fatalf("Could not cleanup: %s", err)
+	}
+	if _, err := client.Exec(createTableQuery); err != nil {
+		log.Fatalf("Could not create table: %s", err)
+	}
+}
+
+func countUsers(t *testing.T) int {
+	t.Helper()
+	var count int
+	assert.NoError(t, client.Get(&count, "SELECT COUNT(*) FROM users"))
+	return count
+}
+
+func TestRunInTxCommit(t *testing.T) {
+	resetUsersTable(t)
+
+	err := client.RunInTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		// Repositories join the transaction through the context
+		_, err := client.Querier(ctx).ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'alice')")
+		return err
+	}, db.Serializable())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, countUsers(t))
+}
+
+func TestRunInTxRollback(t *testing.T) {
+	resetUsersTable(t)
+
+	errFailed := errors.New("failed")
+	err := client.RunInTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'alice')"); err != nil {
+			return err
+		}
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 0, countUsers(t))
+}
+
+func TestRunInTxSavepoints(t *testing.T) {
+	resetUsersTable(t)
+
+	err := client.RunInTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'alice')"); err != nil {
+			return err
+		}
+
+		// A failing nested transaction only rolls back to its savepoint
+		nestedErr := client.RunInTx(ctx, func(ctx context.Context, nested *sqlx.Tx) error {
+			assert.Same(t, tx, nested)
+			if _, err := nested.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('2', 'bob')"); err != nil {
+				return err
+			}
+			_, err := nested.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'duplicate')")
+			return err
+		})
+		assert.Error(t, nestedErr)
+
+		return client.RunInTx(ctx, func(ctx context.Context, nested *sqlx.Tx) error {
+			_, err := nested.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('3', 'carol')")
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	var names []string
+	assert.NoError(t, client.Select(&names, "SELECT name FROM users ORDER BY id"))
+	assert.Equal(t, []string{"alice", "carol"}, names)
+}
+
+func TestWithTxnJoinsRunInTx(t *testing.T) {
+	resetUsersTable(t)
+
+	err := client.RunInTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		if _, err := client.Querier(ctx).ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'alice')"); err != nil {
+			return err
+		}
+
+		// WithTxn joins the transaction of the context within a savepoint
+		nestedErr := client.WithTxn(ctx, sql.TxOptions{}, func(nested *sqlx.Tx) error {
+			assert.Same(t, tx, nested)
+			_, err := nested.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'duplicate')")
+			return err
+		})
+		assert.Error(t, nestedErr)
+
+		return client.WithTxn(ctx, sql.TxOptions{}, func(nested *sqlx.Tx) error {
+			_, err := nested.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('2', 'bob')")
+			return err
+		})
+	})
+	assert.NoError(t, err)
+
+	var names []string
+	assert.NoError(t, client.Select(&names, "SELECT name FROM users ORDER BY id"))
+	assert.Equal(t, []string{"alice", "bob"}, names)
+}
+
+func TestRunInTxRetries(t *testing.T) {
+	resetUsersTable(t)
+
+	attempts := 0
+	err := client.RunInTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		attempts++
+		if _, err := tx.ExecContext(ctx, "INSERT INTO users (id, name) VALUES ('1', 'alice')"); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return &pq.Error{Code: "40001"}
+		}
+		return nil
+	}, db.WithBackoff(time.Millisecond, 5*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 1, countUsers(t))
+
+	attempts = 0
+	err = client.RunInTx(context.Background(), func(ctx context.Context, tx *sqlx.Tx) error {
+		attempts++
+		return &pq.Error{Code: "40P01"}
+	}, db.WithRetries(1), db.WithBackoff(time.Millisecond, time.Millisecond))
+	assert.True(t, db.IsRetryable(err))
+	assert.Equal(t, 2, attempts)
+}
+
+func TestWithTxnPanicRollsBack(t *testing.T) {
+	if _, err := client.Exec(dropTableQuery); err != nil {
+		log.Fatalf("Could not cleanup: %s", err)
+	}
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = client.WithTxn(context.Background(), sql.TxOptions{}, func(tx *sqlx.Tx) error {
+			if _, err := tx.Exec(createTableQuery); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	})
+
+	// Table should not be created
+	var tableExist bool
+	result := client.QueryRow(checkTableQuery)
+	result.Scan(&tableExist)
+	assert.Equal(t, false, tableExist)
+}
+
+func TestIsRetryable(t *testing.T) {
+	assert.True(t, db.IsRetryable(fmt.Errorf("insert: %w", &pq.Error{Code: "40001"})))
+	assert.True(t, db.IsRetryable(&pq.Error{Code: "40P01"}))
+	assert.False(t, db.IsRetryable(&pq.Error{Code: "23505"}))
+	assert.False(t, db.IsRetryable(errors.New("40001")))
+}